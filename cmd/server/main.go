@@ -1,43 +1,107 @@
 package main
 
 import (
+	"context"
+	"errors"
 	log "github.com/sirupsen/logrus"
-	apicontext "github.com/softwareplace/goserve/context"
 	"github.com/softwareplace/goserve/logger"
-	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/config"
 	"github.com/softwareplace/mock-server/pkg/env"
 	"github.com/softwareplace/mock-server/pkg/handler"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
+// shutdownTimeout bounds the wait for the requests in flight when the server moves to another port.
+const shutdownTimeout = 10 * time.Second
+
 var (
-	appEnv    *env.AppEnv
-	appServer server.Api[*apicontext.DefaultContext]
+	appEnv     *env.AppEnv
+	httpServer *http.Server
+	serverPort string
+	serverMu   sync.Mutex
 )
 
 func init() {
 	logger.LogSetup()
-	appEnv = env.GetAppEnv()
 }
 
 func main() {
+	appEnv = env.GetAppEnv()
 	handler.LoadResponses(onFileChangeDetected)
 	select {}
 }
 
 func onFileChangeDetected(restartServer bool) {
+	port := appEnv.Port
 	if restartServer {
-		if appServer != nil {
-			err := appServer.StopServer()
-			if err != nil {
-				log.Fatalf("Failed to stop server: %v", err)
-			}
+		port = configuredPort()
+	}
+
+	if err := serve(port, handler.Default().Handler()); err != nil {
+		if !restartServer {
+			log.Fatalf("Server failed: %v", err)
+		}
+		log.Errorf("Failed to move the server to the port %s, it keeps serving on the previous port: %v", port, err)
+	}
+}
+
+// configuredPort reads the configuration file again, returning the port it sets, or the port of the startup
+// when it sets none. The context path cannot change on a running server, so its change is only reported.
+func configuredPort() string {
+	serverConfig := config.Load(appEnv.ServerConfig)
+	if serverConfig == nil {
+		return appEnv.Port
+	}
+
+	if serverConfig.ContextPath != "" && strings.TrimSuffix(serverConfig.ContextPath, "/")+"/" != appEnv.ContextPath {
+		log.Warnf("The context path changed to %s, restart the server to apply it", serverConfig.ContextPath)
+	}
+
+	if serverConfig.Port != "" {
+		return serverConfig.Port
+	}
+	return appEnv.Port
+}
+
+// serve starts the server on the port. When the server already runs on another port, the new port is bound
+// first, then the running server is gracefully shut down, completing the requests in flight. A server already
+// running on the port keeps serving the reloaded mocks.
+func serve(port string, handler http.Handler) error {
+	serverMu.Lock()
+	defer serverMu.Unlock()
+
+	if httpServer != nil && serverPort == port {
+		log.Infof("Mocks reloaded, serving the new routes at http://localhost:%s%s", port, appEnv.ContextPath)
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+
+	if httpServer != nil {
+		log.Infof("The port changed from %s to %s, moving the server to the new port", serverPort, port)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Errorf("Failed to shut down the server on the port %s: %v", serverPort, err)
 		}
 	}
 
-	appServer = server.Default().
-		Port(appEnv.Port).
-		ContextPath(appEnv.ContextPath).
-		EmbeddedServer(handler.Register).
-		CustomNotFoundHandler(handler.NotFound).
-		StartServerInGoroutine()
+	server := &http.Server{Handler: handler}
+	httpServer, serverPort = server, port
+
+	log.Infof("Server started at http://localhost:%s%s", port, appEnv.ContextPath)
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+	return nil
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func freePort(t *testing.T) string {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func reachable(port string) bool {
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%s/", port))
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func TestServeRebindsOnPortChange(t *testing.T) {
+	appEnv = &env.AppEnv{ContextPath: "/"}
+	defer func() {
+		if httpServer != nil {
+			_ = httpServer.Close()
+		}
+		httpServer, serverPort = nil, ""
+	}()
+
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	firstPort, secondPort := freePort(t), freePort(t)
+	if err := serve(firstPort, okHandler); err != nil {
+		t.Fatalf("Failed to start the server: %v", err)
+	}
+	started := httpServer
+	if !reachable(firstPort) {
+		t.Fatalf("Expected the server to answer on the port %s", firstPort)
+	}
+
+	t.Run("keeps the running server when the port is unchanged", func(t *testing.T) {
+		if err := serve(firstPort, okHandler); err != nil {
+			t.Fatalf("Expected the reload to succeed, got %v", err)
+		}
+		if httpServer != started || !reachable(firstPort) {
+			t.Errorf("Expected the server to keep running on the port %s", firstPort)
+		}
+	})
+
+	t.Run("keeps the running server when the new port cannot be bound", func(t *testing.T) {
+		busy, err := net.Listen("tcp", ":0")
+		if err != nil {
+			t.Fatalf("Failed to bind a port: %v", err)
+		}
+		defer busy.Close()
+
+		busyPort := strconv.Itoa(busy.Addr().(*net.TCPAddr).Port)
+		if err := serve(busyPort, okHandler); err == nil {
+			t.Errorf("Expected the port %s in use to be rejected", busyPort)
+		}
+		if httpServer != started || !reachable(firstPort) {
+			t.Errorf("Expected the server to keep running on the port %s", firstPort)
+		}
+	})
+
+	t.Run("moves the server to the new port", func(t *testing.T) {
+		if err := serve(secondPort, okHandler); err != nil {
+			t.Fatalf("Failed to move the server: %v", err)
+		}
+		if !reachable(secondPort) {
+			t.Errorf("Expected the server to answer on the new port %s", secondPort)
+		}
+		if reachable(firstPort) {
+			t.Errorf("Expected the previous port %s to be released", firstPort)
+		}
+	})
+}
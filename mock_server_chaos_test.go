@@ -0,0 +1,135 @@
+package mock_server
+
+import (
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/handler"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChaos(t *testing.T) {
+	env.SetAppEnv(appEnv)
+
+	var appServer server.Api[*apicontext.DefaultContext]
+
+	// Load mock responses
+	handler.LoadResponses(func(restartServer bool) {
+		appServer = server.Default().
+			ContextPath(appEnv.ContextPath).
+			EmbeddedServer(handler.Register)
+	})
+
+	setChaos := func(t *testing.T, config string) {
+		if rr := serveRequest(t, appServer, "PUT", "/__admin/chaos", config); rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d setting the chaos, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+	}
+
+	t.Cleanup(func() {
+		setChaos(t, `{"enabled": false}`)
+	})
+
+	t.Run("errors are injected on the routes in scope only", func(t *testing.T) {
+		setChaos(t, `{"enabled": true, "errorRate": 1, "errorStatusCodes": [503], "paths": ["/api/chaos/*"], "methods": ["GET"]}`)
+
+		if rr := serveRequest(t, appServer, "GET", "/api/chaos/items", ""); rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+
+		if rr := serveRequest(t, appServer, "GET", "/api/strategies/servers", ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected the route out of scope to return %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := serveRequest(t, appServer, "GET", "/__admin/chaos", ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected the admin routes to be left alone, got %d", rr.Code)
+		}
+	})
+
+	t.Run("errors are injected on unknown routes", func(t *testing.T) {
+		setChaos(t, `{"enabled": true, "errorRate": 1, "errorStatusCodes": [502]}`)
+
+		req := httptest.NewRequest("GET", "/api/chaos/unknown", nil)
+		rr := httptest.NewRecorder()
+		handler.NotFound(rr, req)
+
+		if rr.Code != http.StatusBadGateway {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadGateway, rr.Code)
+		}
+	})
+
+	t.Run("disabling the chaos restores the regular responses", func(t *testing.T) {
+		setChaos(t, `{"enabled": true, "errorRate": 1}`)
+
+		if rr := serveRequest(t, appServer, "POST", "/__admin/chaos/disable", ""); rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d disabling the chaos, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := serveRequest(t, appServer, "GET", "/api/chaos/items", ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := serveRequest(t, appServer, "POST", "/__admin/chaos/enable", ""); rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d enabling the chaos, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := serveRequest(t, appServer, "GET", "/api/chaos/items", ""); rr.Code == http.StatusOK {
+			t.Errorf("Expected an error once the chaos is enabled again")
+		}
+	})
+
+	t.Run("latency is added to the requests", func(t *testing.T) {
+		setChaos(t, `{"enabled": true, "latencyRate": 1, "latency": {"distribution": "uniform", "min": 100, "max": 120}}`)
+
+		start := time.Now()
+		rr := serveRequest(t, appServer, "GET", "/api/chaos/items", "")
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("Expected at least 100ms of latency, got %v", elapsed)
+		}
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("the same seed reproduces the same chaos", func(t *testing.T) {
+		run := func() []int {
+			setChaos(t, `{"enabled": true, "seed": 42, "errorRate": 0.5}`)
+
+			var statuses []int
+			for range 20 {
+				statuses = append(statuses, serveRequest(t, appServer, "GET", "/api/chaos/items", "").Code)
+			}
+			return statuses
+		}
+
+		first, second := run(), run()
+		if !slices.Equal(first, second) {
+			t.Errorf("Expected the same statuses with the same seed, got %v and %v", first, second)
+		}
+
+		hasError := slices.ContainsFunc(first, func(status int) bool {
+			return status != http.StatusOK
+		})
+		if !hasError || !slices.Contains(first, http.StatusOK) {
+			t.Errorf("Expected both errors and regular responses, got %v", first)
+		}
+	})
+
+	t.Run("connections are dropped", func(t *testing.T) {
+		setChaos(t, `{"enabled": true, "dropRate": 1}`)
+
+		// Dropping the connection hijacks it, so a real server is required
+		testServer := httptest.NewServer(appServer.Router())
+		defer testServer.Close()
+
+		client := &http.Client{Timeout: 500 * time.Millisecond}
+		if _, err := client.Get(testServer.URL + "/api/chaos/items"); err == nil {
+			t.Fatalf("Expected the request to fail")
+		}
+	})
+}
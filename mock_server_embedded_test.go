@@ -0,0 +1,229 @@
+package mock_server
+
+import (
+	"fmt"
+	"github.com/softwareplace/mock-server/pkg/mockserver"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func getBody(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to request %s: %v", url, err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("Failed to read the response of %s: %v", url, err)
+	}
+	return response.StatusCode, strings.TrimSpace(string(data))
+}
+
+func TestEmbeddedMockServer(t *testing.T) {
+	t.Run("runs independent instances in parallel", func(t *testing.T) {
+		for instance := 1; instance <= 3; instance++ {
+			t.Run(fmt.Sprintf("instance %d", instance), func(t *testing.T) {
+				t.Parallel()
+
+				server := mockserver.New(mockserver.Options{}).Start()
+				defer server.Close()
+
+				_, err := server.Stub(http.MethodGet, "/api/instance").
+					WillReturn(http.StatusOK, map[string]any{"instance": instance}).
+					Register()
+				if err != nil {
+					t.Fatalf("Failed to register the stub: %v", err)
+				}
+
+				status, body := getBody(t, server.URL()+"/api/instance")
+				expected := fmt.Sprintf(`{"instance":%d}`, instance)
+				if status != http.StatusOK || body != expected {
+					t.Errorf("Expected %s, got %d: %s", expected, status, body)
+				}
+			})
+		}
+	})
+
+	t.Run("matches the stubs of a route against each other", func(t *testing.T) {
+		server := mockserver.New(mockserver.Options{}).Start()
+		defer server.Close()
+
+		stubs := []*mockserver.Stub{
+			server.Stub(http.MethodGet, "/api/users/{id}").
+				WithPathValue("id", "1").
+				WillReturn(http.StatusOK, map[string]any{"id": 1, "name": "John"}),
+			server.Stub(http.MethodGet, "/api/users/{id}").
+				WithPathValue("id", "2").
+				WithQuery("details", "true").
+				WithResponseHeader("X-User", "2").
+				WillReturn(http.StatusOK, "plain user 2").
+				WithContentType("text/plain"),
+			server.Stub(http.MethodGet, "/api/users/{id}").
+				WillReturn(http.StatusNotFound, map[string]any{"message": "unknown user"}),
+		}
+		for _, stub := range stubs {
+			if _, err := stub.Register(); err != nil {
+				t.Fatalf("Failed to register the stub: %v", err)
+			}
+		}
+
+		if mocks := server.Mocks(); len(mocks) != 1 || len(mocks[0].Response.Bodies) != 3 {
+			t.Fatalf("Expected the stubs to share one mock, got %+v", mocks)
+		}
+
+		tests := []struct {
+			path           string
+			expectedStatus int
+			expectedBody   string
+		}{
+			{"/api/users/1", http.StatusOK, `{"id":1,"name":"John"}`},
+			{"/api/users/2?details=true", http.StatusOK, "plain user 2"},
+			{"/api/users/3", http.StatusNotFound, `{"message":"unknown user"}`},
+		}
+		for _, tt := range tests {
+			status, body := getBody(t, server.URL()+tt.path)
+			if status != tt.expectedStatus || body != tt.expectedBody {
+				t.Errorf("%s: expected %d %s, got %d: %s", tt.path, tt.expectedStatus, tt.expectedBody, status, body)
+			}
+		}
+	})
+
+	t.Run("matches a templated route without path value", func(t *testing.T) {
+		server := mockserver.New(mockserver.Options{}).Start()
+		defer server.Close()
+
+		_, err := server.Stub(http.MethodGet, "/api/users/{id}").
+			WithHeader("Authorization", "Bearer token").
+			WillReturn(http.StatusOK, map[string]any{"authorized": true}).
+			Register()
+		if err != nil {
+			t.Fatalf("Failed to register the stub: %v", err)
+		}
+
+		request, _ := http.NewRequest(http.MethodGet, server.URL()+"/api/users/7", nil)
+		request.Header.Set("Authorization", "Bearer token")
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("Failed to request the stub: %v", err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+
+		if response.StatusCode != http.StatusOK || string(body) != `{"authorized":true}` {
+			t.Errorf("Expected any id to match the stub, got %d: %s", response.StatusCode, body)
+		}
+	})
+
+	t.Run("loads the mock files and overrides them with stubs", func(t *testing.T) {
+		server := mockserver.New(mockserver.Options{MockPath: appEnv.MockPath})
+		defer server.Close()
+
+		serve := func(path string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			return rr
+		}
+
+		if rr := serve("/api/products/1"); rr.Code != http.StatusOK {
+			t.Fatalf("Expected the mock file to be served, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		_, err := server.Stub(http.MethodGet, "/api/stubbed").
+			WithHeader("X-Tenant", "acme").
+			WillReturn(http.StatusAccepted, map[string]any{"tenant": "acme"}).
+			Register()
+		if err != nil {
+			t.Fatalf("Failed to register the stub: %v", err)
+		}
+
+		request := httptest.NewRequest(http.MethodGet, "/api/stubbed", nil)
+		request.Header.Set("X-Tenant", "acme")
+		rr := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rr, request)
+		if rr.Code != http.StatusAccepted {
+			t.Errorf("Expected the stub to be served, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		server.Reset()
+		if rr := serve("/api/stubbed"); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected the stub to be removed by the reset, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if rr := serve("/api/products/1"); rr.Code != http.StatusOK {
+			t.Errorf("Expected the mock file to be kept by the reset, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("serves requests while the mocks are reloaded", func(t *testing.T) {
+		server := mockserver.New(mockserver.Options{MockPath: appEnv.MockPath}).Start()
+		defer server.Close()
+
+		var wg sync.WaitGroup
+		for worker := 0; worker < 4; worker++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for request := 0; request < 20; request++ {
+					if status, body := getBody(t, server.URL()+"/api/products/1"); status != http.StatusOK {
+						t.Errorf("Expected the mock file to be served during the reload, got %d: %s", status, body)
+						return
+					}
+				}
+			}()
+		}
+
+		for stub := 0; stub < 10; stub++ {
+			_, err := server.Stub(http.MethodGet, fmt.Sprintf("/api/reloaded/%d", stub)).
+				WillReturn(http.StatusOK, "reloaded").
+				Register()
+			if err != nil {
+				t.Errorf("Failed to register the stub: %v", err)
+			}
+		}
+		wg.Wait()
+	})
+
+	t.Run("keeps every mock added concurrently", func(t *testing.T) {
+		server := mockserver.New(mockserver.Options{MockPath: appEnv.MockPath})
+		defer server.Close()
+		fileMocks := len(server.Mocks())
+
+		var wg sync.WaitGroup
+		for stub := 0; stub < 20; stub++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var body any = "concurrent"
+				_, err := server.AddMock(model.MockConfigResponse{
+					Request:  model.RequestConfig{Method: http.MethodGet, Path: fmt.Sprintf("/api/concurrent/%d", stub)},
+					Response: model.ResponseConfig{Bodies: []model.ResponseBody{{Body: &body}}},
+				})
+				if err != nil {
+					t.Errorf("Failed to add the mock: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if mocks := server.Mocks(); len(mocks) != fileMocks+20 {
+			t.Errorf("Expected the 20 mocks to be kept along with the %d mock files, got %d mocks", fileMocks, len(mocks))
+		}
+	})
+
+	t.Run("rejects an invalid mock", func(t *testing.T) {
+		server := mockserver.New(mockserver.Options{})
+		defer server.Close()
+
+		if _, err := server.AddMock(model.MockConfigResponse{}); err == nil {
+			t.Errorf("Expected the mock without method and path to be rejected")
+		}
+	})
+}
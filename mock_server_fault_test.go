@@ -0,0 +1,89 @@
+package mock_server
+
+import (
+	"encoding/json"
+	"errors"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/handler"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFaultInjection(t *testing.T) {
+	env.SetAppEnv(appEnv)
+
+	var appServer server.Api[*apicontext.DefaultContext]
+
+	// Load mock responses
+	handler.LoadResponses(func(restartServer bool) {
+		appServer = server.Default().
+			ContextPath(appEnv.ContextPath).
+			EmbeddedServer(handler.Register)
+	})
+
+	// The faults hijack the connection, so a real server is required
+	testServer := httptest.NewServer(appServer.Router())
+	defer testServer.Close()
+
+	client := &http.Client{Timeout: 500 * time.Millisecond}
+
+	t.Run("empty-response closes the connection without any reply", func(t *testing.T) {
+		_, err := client.Get(testServer.URL + "/api/faults/empty")
+		if err == nil {
+			t.Fatalf("Expected the request to fail")
+		}
+	})
+
+	t.Run("connection-reset resets the connection", func(t *testing.T) {
+		_, err := client.Get(testServer.URL + "/api/faults/reset")
+		if err == nil {
+			t.Fatalf("Expected the request to fail")
+		}
+	})
+
+	t.Run("hang-after-headers sends the headers then hangs", func(t *testing.T) {
+		response, err := client.Get(testServer.URL + "/api/faults/hang")
+		if err != nil {
+			t.Fatalf("Expected the headers to be received, got: %v", err)
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, response.StatusCode)
+		}
+
+		if _, err = io.ReadAll(response.Body); err == nil {
+			t.Fatalf("Expected reading the body to time out")
+		}
+	})
+
+	t.Run("truncated-body sends less than the Content-Length", func(t *testing.T) {
+		response, err := client.Get(testServer.URL + "/api/faults/truncated")
+		if err != nil {
+			t.Fatalf("Expected the headers to be received, got: %v", err)
+		}
+		defer response.Body.Close()
+
+		if _, err = io.ReadAll(response.Body); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("Expected an unexpected EOF reading the body, got: %v", err)
+		}
+	})
+
+	t.Run("malformed-json sends a corrupted body", func(t *testing.T) {
+		response, err := client.Get(testServer.URL + "/api/faults/malformed")
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer response.Body.Close()
+
+		var body any
+		if err = json.NewDecoder(response.Body).Decode(&body); err == nil {
+			t.Fatalf("Expected the body to be malformed, got: %v", body)
+		}
+	})
+}
@@ -4,22 +4,23 @@ import (
 	"encoding/base64"
 	"fmt"
 	apicontext "github.com/softwareplace/goserve/context"
-	"github.com/softwareplace/goserve/logger"
 	"github.com/softwareplace/goserve/request"
 	"github.com/softwareplace/goserve/server"
 	"github.com/softwareplace/mock-server/pkg/env"
 	"github.com/softwareplace/mock-server/pkg/handler"
+	"github.com/softwareplace/mock-server/pkg/mockserver"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
 func TestServerReloading(t *testing.T) {
-	logger.LogSetup()
-
 	mockFilePath := "./dev/mock/.temp"
 	mockFileName := "product-test-mode.yaml"
 
@@ -31,13 +32,20 @@ func TestServerReloading(t *testing.T) {
 		WithPath("v1/products/server/reload/1000")
 
 	env.SetAppEnv(appEnv)
-	var appServer server.Api[*apicontext.DefaultContext]
-	serverWasReloaded := false
+	// The server is reloaded from the file watcher goroutine
+	var (
+		mu                sync.Mutex
+		appServer         server.Api[*apicontext.DefaultContext]
+		serverWasReloaded bool
+	)
 
 	handler.LoadResponses(func(restartServer bool) {
+		mu.Lock()
+		defer mu.Unlock()
 		serverWasReloaded = restartServer
 		appServer = createServer(appServer, restartServer)
 	})
+	waitForServer(t, "localhost:"+appEnv.Port)
 
 	t.Run("expects that return resource not found before add new mock file", func(t *testing.T) {
 		requestService := request.NewService()
@@ -73,10 +81,14 @@ func TestServerReloading(t *testing.T) {
 
 		time.Sleep(1 * time.Second)
 
-		if serverWasReloaded {
+		mu.Lock()
+		reloaded := serverWasReloaded
+		mu.Unlock()
+
+		if reloaded {
 			log.Println("Server was reloaded successfully.")
 		} else {
-			t.Fatalf("Expected server to be reloaded, but got: %v", serverWasReloaded)
+			t.Fatalf("Expected server to be reloaded, but got: %v", reloaded)
 		}
 
 		requestService := request.NewService()
@@ -108,6 +120,126 @@ func TestServerReloading(t *testing.T) {
 	_removeMockTestFile(t, mockFileFullPath)
 }
 
+func TestHotReloadWithoutRestart(t *testing.T) {
+	mockPath := t.TempDir()
+	writeMock := func(name string, path string) {
+		mock := fmt.Sprintf("request:\n  path: %q\n  method: GET\nresponse:\n  bodies:\n    - body: %q\n", path, name)
+		if err := os.WriteFile(filepath.Join(mockPath, name+".yaml"), []byte(mock), 0644); err != nil {
+			t.Fatalf("Failed to write the mock %s: %v", name, err)
+		}
+	}
+
+	writeMock("existing", "/api/existing")
+	mockServer := mockserver.New(mockserver.Options{MockPath: mockPath}).Start()
+	defer mockServer.Close()
+
+	// Request the existing mock during the reload, none of the requests may fail
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			response, err := http.Get(mockServer.URL() + "/api/existing")
+			if err != nil {
+				t.Errorf("The request failed during the reload: %v", err)
+				return
+			}
+			_ = response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Errorf("Expected status code 200 during the reload, got %d", response.StatusCode)
+				return
+			}
+		}
+	}()
+
+	writeMock("added", "/api/added")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		response, err := http.Get(mockServer.URL() + "/api/added")
+		if err != nil {
+			t.Fatalf("Failed to request the added mock: %v", err)
+		}
+		_ = response.Body.Close()
+		if response.StatusCode == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the added mock to be served after the reload, got %d", response.StatusCode)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	close(done)
+	wg.Wait()
+}
+
+func TestReloadDebounce(t *testing.T) {
+	mockPath := t.TempDir()
+	writeMock := func(index int) {
+		mock := fmt.Sprintf("request:\n  path: \"/api/burst/%d\"\n  method: GET\nresponse:\n  bodies:\n    - body: \"burst\"\n", index)
+		if err := os.WriteFile(filepath.Join(mockPath, fmt.Sprintf("burst-%d.yaml", index)), []byte(mock), 0644); err != nil {
+			t.Fatalf("Failed to write the mock %d: %v", index, err)
+		}
+	}
+	writeMock(0)
+
+	mockServer := handler.NewServer(&env.AppEnv{MockPath: mockPath, ContextPath: "/"}, nil)
+	defer mockServer.Close()
+
+	reloads := make(chan bool, 16)
+	mockServer.LoadResponses(func(restartServer bool) {
+		if restartServer {
+			reloads <- true
+		}
+	})
+
+	// A burst of changes, each one within the debounce duration of the previous one, is reloaded once
+	for index := 1; index <= 5; index++ {
+		writeMock(index)
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the mocks to be reloaded after the burst of changes")
+	}
+
+	select {
+	case <-reloads:
+		t.Errorf("Expected the burst of changes to be reloaded once")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	if mocks := mockServer.Mocks(); len(mocks) != 6 {
+		t.Errorf("Expected every mock of the burst to be loaded, got %d", len(mocks))
+	}
+}
+
+// waitForServer waits for the server started in the background to accept connections.
+func waitForServer(t *testing.T, address string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		connection, err := net.DialTimeout("tcp", address, 100*time.Millisecond)
+		if err == nil {
+			_ = connection.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("The server at %s did not start: %v", address, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func createProjectMockConfig(t *testing.T, mockFilePath string, mockFileFullPath string) error {
 	addMockProductConfig := "cmVxdWVzdDoKICBwYXRoOiAiL3YxL3Byb2R1Y3RzL3NlcnZlci9yZWxvYWQve2lkfSIKICBtZXRob2Q6ICJHRVQiCnJlc3BvbnNlOgogIGNvbnRlbnQtdHlwZTogImFwcGxpY2F0aW9uL2pzb24iCiAgc3RhdHVzLWNvZGU6IDIwMAogIGJvZGllczoKICAgIC0gYm9keToKICAgICAgICBpZDogMTAwMAogICAgICAgIG5hbWU6ICJQcm9kdWN0IgogICAgICAgIGRlc2NyaXB0aW9uOiBUaGlzIGlzIGEgbW9jayBwcm9kdWN0IGRlc2NyaXB0aW9uCiAgICAgICAgYW1vdW50OiAyNTAwLjc1CiAgICAgIG1hdGNoaW5nOgogICAgICAgIHBhdGhzOgogICAgICAgICAgaWQ6IDEwMDAK"
 
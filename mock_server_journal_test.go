@@ -0,0 +1,180 @@
+package mock_server
+
+import (
+	"encoding/json"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/handler"
+	"github.com/softwareplace/mock-server/pkg/mockserver"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestJournal(t *testing.T) {
+	env.SetAppEnv(appEnv)
+
+	var appServer server.Api[*apicontext.DefaultContext]
+
+	// Load mock responses
+	handler.LoadResponses(func(restartServer bool) {
+		appServer = server.Default().
+			ContextPath(appEnv.ContextPath).
+			EmbeddedServer(handler.Register)
+	})
+
+	if rr := serveRequest(t, appServer, "DELETE", "/__admin/requests", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected status code %d clearing the journal, got %d", http.StatusNoContent, rr.Code)
+	}
+
+	serveRequest(t, appServer, "POST", "/api/orders", `{"customer": {"id": 42}, "items": []}`)
+	serveRequest(t, appServer, "POST", "/api/orders", `{"customer": {"id": 42, "type": "VIP"}, "items": [{"sku": "SKU-2"}]}`)
+	serveRequest(t, appServer, "GET", "/api/strategies/servers?region=eu", "")
+	serveRequest(t, appServer, "GET", "/__admin/scenarios", "")
+	handler.NotFound(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/unknown", nil))
+
+	findRequests := func(t *testing.T, query string) []map[string]any {
+		rr := serveRequest(t, appServer, "GET", "/__admin/requests"+query, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var entries []map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("Failed to decode the journal %s: %v", rr.Body.String(), err)
+		}
+		return entries
+	}
+
+	t.Run("records every request but the admin ones", func(t *testing.T) {
+		entries := findRequests(t, "")
+		if len(entries) != 4 {
+			t.Fatalf("Expected 4 requests, got %d: %v", len(entries), entries)
+		}
+
+		first := entries[0]
+		if first["method"] != "POST" || first["uri"] != "/api/orders" || first["status"] != float64(http.StatusCreated) {
+			t.Errorf("Unexpected first request %v", first)
+		}
+
+		if first["bodyIndex"] != float64(2) || first["mockFilePath"] == "" || first["body"] == "" {
+			t.Errorf("Expected the matched mock and body to be recorded, got %v", first)
+		}
+
+		if last := entries[3]; last["status"] != float64(http.StatusNotFound) || last["mockId"] != nil {
+			t.Errorf("Expected the unknown request to be recorded without a mock, got %v", last)
+		}
+	})
+
+	t.Run("filters the requests", func(t *testing.T) {
+		if entries := findRequests(t, "?method=POST&path=/api/orders"); len(entries) != 2 {
+			t.Errorf("Expected 2 requests, got %d", len(entries))
+		}
+
+		if entries := findRequests(t, "?path=/api/*/servers"); len(entries) != 1 {
+			t.Errorf("Expected 1 request, got %d", len(entries))
+		}
+
+		if entries := findRequests(t, "?status=404"); len(entries) != 1 {
+			t.Errorf("Expected 1 request, got %d", len(entries))
+		}
+
+		entries := findRequests(t, "?method=POST&limit=1")
+		if len(entries) != 1 || entries[0]["bodyIndex"] != float64(1) {
+			t.Errorf("Expected the latest POST request, got %v", entries)
+		}
+	})
+
+	tests := []struct {
+		name           string
+		verification   string
+		expectedStatus int
+		expectedCount  float64
+	}{
+		{
+			name:           "exact count",
+			verification:   `{"method": "POST", "path": "/api/orders", "count": 2}`,
+			expectedStatus: http.StatusOK,
+			expectedCount:  2,
+		},
+		{
+			name:           "wrong count",
+			verification:   `{"method": "POST", "path": "/api/orders", "count": 1}`,
+			expectedStatus: http.StatusExpectationFailed,
+			expectedCount:  2,
+		},
+		{
+			name:           "body matching",
+			verification:   `{"method": "POST", "path": "/api/orders", "body": {"jsonPath": {"$.customer.type": "VIP"}}, "count": 1}`,
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+		{
+			name:           "query matching",
+			verification:   `{"path": "/api/strategies/servers", "queries": {"region": {"oneOf": ["eu", "us"]}}}`,
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+		{
+			name:           "never called",
+			verification:   `{"method": "DELETE", "path": "/api/orders"}`,
+			expectedStatus: http.StatusExpectationFailed,
+			expectedCount:  0,
+		},
+		{
+			name:           "at most",
+			verification:   `{"method": "DELETE", "path": "/api/orders", "atMost": 0}`,
+			expectedStatus: http.StatusOK,
+			expectedCount:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("verifies "+tt.name, func(t *testing.T) {
+			rr := serveRequest(t, appServer, "POST", "/__admin/requests/verify", tt.verification)
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+
+			var result map[string]any
+			if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to decode the verification %s: %v", rr.Body.String(), err)
+			}
+
+			if result["count"] != tt.expectedCount {
+				t.Errorf("Expected count %v, got %v", tt.expectedCount, result["count"])
+			}
+		})
+	}
+}
+
+func TestRequestJournalBodySize(t *testing.T) {
+	server := mockserver.New(mockserver.Options{
+		Config: &model.MockServerConfig{JournalBodySize: 16},
+	}).Start()
+	defer server.Close()
+
+	payload := `{"name":"a file name longer than the journal body size"}`
+	_, err := server.Stub(http.MethodPost, "/api/uploads").
+		WithBody(model.BodyMatching{EqualTo: map[string]any{"name": "a file name longer than the journal body size"}}).
+		WillReturn(http.StatusCreated, "uploaded").
+		Register()
+	if err != nil {
+		t.Fatalf("Failed to register the stub: %v", err)
+	}
+
+	if status, body := doRequest(t, http.MethodPost, server.URL()+"/api/uploads", payload, "application/json"); status != http.StatusCreated {
+		t.Fatalf("Expected the whole body to be matched, got %d: %s", status, body)
+	}
+
+	status, body := getBody(t, server.URL()+"/__admin/requests?path=/api/uploads")
+	var entries []map[string]any
+	if err := json.Unmarshal([]byte(body), &entries); err != nil || status != http.StatusOK || len(entries) != 1 {
+		t.Fatalf("Expected the upload to be recorded, got %d: %s", status, body)
+	}
+	if entries[0]["body"] != payload[:16] || entries[0]["bodyTruncated"] != true {
+		t.Errorf("Expected the body to be truncated to 16 bytes, got %v", entries[0])
+	}
+}
@@ -0,0 +1,88 @@
+package mock_server
+
+import (
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/handler"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLatency(t *testing.T) {
+	env.SetAppEnv(appEnv)
+
+	var appServer server.Api[*apicontext.DefaultContext]
+
+	// Load mock responses
+	handler.LoadResponses(func(restartServer bool) {
+		appServer = server.Default().
+			ContextPath(appEnv.ContextPath).
+			EmbeddedServer(handler.Register)
+	})
+
+	tests := []struct {
+		name         string
+		path         string
+		minElapsed   time.Duration
+		maxElapsed   time.Duration
+		expectedBody string
+	}{
+		{
+			name:         "fixed delay of the response",
+			path:         "/api/latency",
+			minElapsed:   100 * time.Millisecond,
+			maxElapsed:   200 * time.Millisecond,
+			expectedBody: `{"kind":"fixed"}`,
+		},
+		{
+			name:         "zero delay of the body overrides the delay of the response",
+			path:         "/api/latency?kind=immediate",
+			minElapsed:   0,
+			maxElapsed:   50 * time.Millisecond,
+			expectedBody: `{"kind":"immediate"}`,
+		},
+		{
+			name:         "uniform latency of the body takes precedence over the delay of the response",
+			path:         "/api/latency?kind=uniform",
+			minElapsed:   100 * time.Millisecond,
+			maxElapsed:   250 * time.Millisecond,
+			expectedBody: `{"kind":"uniform"}`,
+		},
+		{
+			name:         "percentiles latency is clamped between min and max",
+			path:         "/api/latency?kind=percentiles",
+			minElapsed:   50 * time.Millisecond,
+			maxElapsed:   220 * time.Millisecond,
+			expectedBody: `{"kind":"percentiles"}`,
+		},
+		{
+			name:         "body is throttled to the bytes per second",
+			path:         "/api/latency?kind=throttled",
+			minElapsed:   300 * time.Millisecond,
+			maxElapsed:   800 * time.Millisecond,
+			expectedBody: "0123456789012345678901234567890123456789",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			rr := serveRequest(t, appServer, "GET", tt.path, "")
+			elapsed := time.Since(start)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			if elapsed < tt.minElapsed || elapsed > tt.maxElapsed {
+				t.Errorf("Expected the response within %v and %v, got %v", tt.minElapsed, tt.maxElapsed, elapsed)
+			}
+
+			if rr.Body.String() != tt.expectedBody && !jsonDeepEqual(rr.Body.Bytes(), []byte(tt.expectedBody)) {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
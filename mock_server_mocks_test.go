@@ -0,0 +1,213 @@
+package mock_server
+
+import (
+	"encoding/json"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/handler"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdminMocks(t *testing.T) {
+	env.SetAppEnv(appEnv)
+
+	var (
+		mu        sync.Mutex
+		appServer server.Api[*apicontext.DefaultContext]
+		reloaded  = make(chan bool, 16)
+	)
+
+	// Load mock responses, the server is rebuilt in the background once the mocks change
+	handler.LoadResponses(func(restartServer bool) {
+		mu.Lock()
+		appServer = server.Default().
+			ContextPath(appEnv.ContextPath).
+			EmbeddedServer(handler.Register)
+		mu.Unlock()
+
+		if restartServer {
+			reloaded <- true
+		}
+	})
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		mu.Lock()
+		defer mu.Unlock()
+		return serveRequest(t, appServer, method, path, body)
+	}
+
+	waitReload := func() {
+		select {
+		case <-reloaded:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected the server to be reloaded")
+		}
+	}
+
+	t.Cleanup(func() {
+		serve("POST", "/__admin/mocks/reset", "")
+		waitReload()
+	})
+
+	stub := `{
+		"id": "runtime-greeting",
+		"request": {"method": "GET", "path": "/api/runtime/greeting"},
+		"response": {"statusCode": 200, "bodies": [{"body": {"message": "hello"}}]}
+	}`
+
+	t.Run("lists the mocks loaded from files", func(t *testing.T) {
+		rr := serve("GET", "/__admin/mocks", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var mocks []map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &mocks); err != nil || len(mocks) == 0 {
+			t.Fatalf("Expected the loaded mocks, got %s", rr.Body.String())
+		}
+
+		if mocks[0]["id"] == "" || mocks[0]["mockFilePath"] == "" {
+			t.Errorf("Expected the mocks to have an id and a file path, got %v", mocks[0])
+		}
+	})
+
+	t.Run("creates a mock without writing files", func(t *testing.T) {
+		if rr := serve("GET", "/api/runtime/greeting", ""); rr.Code != http.StatusNotFound {
+			t.Fatalf("Expected status code %d before the mock is created, got %d", http.StatusNotFound, rr.Code)
+		}
+
+		if rr := serve("POST", "/__admin/mocks", stub); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+		waitReload()
+
+		rr := serve("GET", "/api/runtime/greeting", "")
+		if rr.Code != http.StatusOK || !jsonDeepEqual(rr.Body.Bytes(), []byte(`{"message":"hello"}`)) {
+			t.Errorf("Expected the created mock to be served, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		if rr := serve("POST", "/__admin/mocks", stub); rr.Code != http.StatusConflict {
+			t.Errorf("Expected status code %d creating the same mock twice, got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("rejects an invalid mock", func(t *testing.T) {
+		rr := serve("POST", "/__admin/mocks", `{"request": {"method": "GET"}}`)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("rejects an id that is not a safe file name", func(t *testing.T) {
+		for _, id := range []string{"../../outside", "nested/mock", `nested\\mock`, ".."} {
+			rr := serve("POST", "/__admin/mocks?persist=true", `{
+				"id": "`+id+`",
+				"request": {"method": "GET", "path": "/api/runtime/traversal"},
+				"response": {"bodies": [{"body": "outside"}]}
+			}`)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d for the id %s, got %d: %s", http.StatusBadRequest, id, rr.Code, rr.Body.String())
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(appEnv.MockPath, "..", "..", "outside.json")); !os.IsNotExist(err) {
+			t.Errorf("Expected no mock file to be written outside of the mock directory, got %v", err)
+		}
+	})
+
+	t.Run("updates a mock", func(t *testing.T) {
+		rr := serve("PUT", "/__admin/mocks/runtime-greeting", `{
+			"request": {"method": "GET", "path": "/api/runtime/greeting"},
+			"response": {"statusCode": 202, "bodies": [{"body": {"message": "updated"}}]}
+		}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		waitReload()
+
+		rr = serve("GET", "/api/runtime/greeting", "")
+		if rr.Code != http.StatusAccepted || !jsonDeepEqual(rr.Body.Bytes(), []byte(`{"message":"updated"}`)) {
+			t.Errorf("Expected the updated mock to be served, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("deletes a mock", func(t *testing.T) {
+		if rr := serve("DELETE", "/__admin/mocks/runtime-greeting", ""); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected status code %d, got %d", http.StatusNoContent, rr.Code)
+		}
+		waitReload()
+
+		if rr := serve("GET", "/api/runtime/greeting", ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d once deleted, got %d", http.StatusNotFound, rr.Code)
+		}
+
+		if rr := serve("GET", "/__admin/mocks/runtime-greeting", ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d once deleted, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("persists a mock to the mock directory", func(t *testing.T) {
+		mockFile := filepath.Join(appEnv.MockPath, "runtime-greeting.json")
+		t.Cleanup(func() {
+			_ = os.Remove(mockFile)
+		})
+
+		if rr := serve("POST", "/__admin/mocks?persist=true", stub); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+		waitReload()
+
+		data, err := os.ReadFile(mockFile)
+		if err != nil {
+			t.Fatalf("Expected the mock to be written to %s: %v", mockFile, err)
+		}
+		if strings.Contains(string(data), "null") || strings.Contains(string(data), "priority") {
+			t.Errorf("Expected the unused settings to be left out:\n%s", data)
+		}
+
+		if rr := serve("GET", "/api/runtime/greeting", ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := serve("DELETE", "/__admin/mocks/runtime-greeting?persist=true", ""); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected status code %d, got %d", http.StatusNoContent, rr.Code)
+		}
+		waitReload()
+
+		if _, err := os.Stat(mockFile); !os.IsNotExist(err) {
+			t.Errorf("Expected the mock file to be removed, got %v", err)
+		}
+	})
+
+	t.Run("never overwrites an existing mock file", func(t *testing.T) {
+		mockFile := filepath.Join(appEnv.MockPath, "runtime-existing.json")
+		existing := `{"request": {"method": "GET", "path": "/api/runtime/existing"}, "response": {"bodies": [{"body": "kept"}]}}`
+		if err := os.WriteFile(mockFile, []byte(existing), 0644); err != nil {
+			t.Fatalf("Failed to write the mock file: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = os.Remove(mockFile)
+		})
+
+		rr := serve("POST", "/__admin/mocks?persist=true", `{
+			"id": "runtime-existing",
+			"request": {"method": "GET", "path": "/api/runtime/replaced"},
+			"response": {"bodies": [{"body": "replaced"}]}
+		}`)
+		if rr.Code != http.StatusConflict {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusConflict, rr.Code, rr.Body.String())
+		}
+
+		if data, err := os.ReadFile(mockFile); err != nil || string(data) != existing {
+			t.Errorf("Expected the existing mock file to be kept, got %s: %v", data, err)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package mock_server
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/handler"
+	"github.com/softwareplace/mock-server/pkg/mockserver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type nearMissReport struct {
+	Request    string `json:"request"`
+	NearMisses []struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+		Reason string `json:"reason"`
+		Bodies []struct {
+			Index  int    `json:"index"`
+			Reason string `json:"reason"`
+		} `json:"bodies"`
+	} `json:"nearMisses"`
+}
+
+func TestNearMissDiagnostics(t *testing.T) {
+	env.SetAppEnv(appEnv)
+
+	var appServer server.Api[*apicontext.DefaultContext]
+
+	// Load mock responses
+	handler.LoadResponses(func(restartServer bool) {
+		appServer = server.Default().
+			ContextPath(appEnv.ContextPath).
+			EmbeddedServer(handler.Register)
+	})
+
+	t.Run("keeps the plain response without the debug mode", func(t *testing.T) {
+		rr := serveRequest(t, appServer, "GET", "/api/orders/2/status", "")
+		if rr.Code != http.StatusNotFound || rr.Body.String() != "Resource not found" {
+			t.Errorf("Expected the plain not found response, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	appEnv.Debug = true
+	t.Cleanup(func() {
+		appEnv.Debug = false
+	})
+
+	decodeReport := func(t *testing.T, rr *httptest.ResponseRecorder) nearMissReport {
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+
+		var report nearMissReport
+		if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to decode the report %s: %v", rr.Body.String(), err)
+		}
+		return report
+	}
+
+	t.Run("lists why each body does not match", func(t *testing.T) {
+		report := decodeReport(t, serveRequest(t, appServer, "GET", "/api/orders/2/status", ""))
+
+		if report.Request != "GET /api/orders/2/status" || len(report.NearMisses) != 1 {
+			t.Fatalf("Expected the mock of the route to be reported, got %+v", report)
+		}
+
+		bodies := report.NearMisses[0].Bodies
+		if len(bodies) != 2 || !strings.Contains(bodies[0].Reason, `path value "id" is "2"`) || bodies[1].Index != 1 {
+			t.Errorf("Expected the path mismatch of both bodies, got %+v", bodies)
+		}
+	})
+
+	t.Run("lists the closest routes of an unknown request", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.NotFound(rr, httptest.NewRequest("POST", "/api/orders/1/status", nil))
+		report := decodeReport(t, rr)
+
+		if len(report.NearMisses) == 0 {
+			t.Fatalf("Expected the closest routes to be reported")
+		}
+
+		closest := report.NearMisses[0]
+		if closest.Path != "/api/orders/{id}/status" || !strings.Contains(closest.Reason, "method is POST, expected GET") {
+			t.Errorf("Expected the route with another method to be the closest, got %+v", closest)
+		}
+	})
+}
+
+func TestBestMatchExplanation(t *testing.T) {
+	hook := &logtest.Hook{}
+	original := log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+	defer log.StandardLogger().ReplaceHooks(original)
+	log.AddHook(hook)
+
+	explained := func(debug bool) bool {
+		hook.Reset()
+		server := mockserver.New(mockserver.Options{Debug: debug}).Start()
+		defer server.Close()
+
+		for _, stub := range []*mockserver.Stub{
+			server.Stub(http.MethodGet, "/api/explained/{id}").WillReturn(http.StatusOK, "any"),
+			server.Stub(http.MethodGet, "/api/explained/{id}").WithPathValue("id", "1").WillReturn(http.StatusOK, "one"),
+		} {
+			if _, err := stub.Register(); err != nil {
+				t.Fatalf("Failed to register the stub: %v", err)
+			}
+		}
+
+		if status, body := getBody(t, server.URL()+"/api/explained/1"); status != http.StatusOK || body != "one" {
+			t.Fatalf("Expected the most specific body, got %d: %s", status, body)
+		}
+
+		for _, entry := range hook.AllEntries() {
+			if strings.HasPrefix(entry.Message, "Body #1 won for request /api/explained/1") && strings.Contains(entry.Message, "#0") {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("explains which body won in the debug mode", func(t *testing.T) {
+		if !explained(true) {
+			t.Errorf("Expected the winning body and the losing ones to be logged")
+		}
+	})
+
+	t.Run("does not explain it without the debug mode", func(t *testing.T) {
+		if explained(false) {
+			t.Errorf("Expected the choice not to be logged")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package mock_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/softwareplace/mock-server/pkg/mockserver"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOnNoMatchPolicies(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "upstream %s", r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	newUserMock := func(onNoMatch string) model.MockConfigResponse {
+		var body any = "mocked user 1"
+		var defaultBody any = "default user"
+		mock := model.MockConfigResponse{
+			Request: model.RequestConfig{Method: http.MethodGet, Path: "/api/users/{id}"},
+			Response: model.ResponseConfig{
+				ContentType: "text/plain",
+				OnNoMatch:   onNoMatch,
+				DefaultBody: &model.ResponseBody{Body: &defaultBody, StatusCode: http.StatusAccepted},
+				Bodies: []model.ResponseBody{
+					{Body: &body, Matching: &model.Matching{Paths: map[string]any{"id": "1"}}},
+				},
+			},
+		}
+		if onNoMatch == model.OnNoMatchRedirect {
+			// Without the policy, the redirect of the mock would apply to every request
+			mock.Redirect.Url = upstream.URL
+		}
+		return mock
+	}
+
+	tests := []struct {
+		name           string
+		onNoMatch      string
+		globalRedirect bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"answers not found by default", "", true, http.StatusNotFound, ""},
+		{"returns the default body", model.OnNoMatchDefaultBody, false, http.StatusAccepted, "default user"},
+		{"forwards to the redirect of the mock", model.OnNoMatchRedirect, false, http.StatusOK, "upstream /api/users/2"},
+		{"forwards to the global redirect", model.OnNoMatchGlobalRedirect, true, http.StatusOK, "upstream /api/users/2"},
+		{"answers not found without global redirect", model.OnNoMatchGlobalRedirect, false, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &model.MockServerConfig{}
+			if tt.globalRedirect {
+				config.RedirectConfig = &model.RedirectConfig{Url: upstream.URL}
+			}
+			server := mockserver.New(mockserver.Options{Config: config}).Start()
+			defer server.Close()
+
+			if _, err := server.AddMock(newUserMock(tt.onNoMatch)); err != nil {
+				t.Fatalf("Failed to add the mock: %v", err)
+			}
+
+			if status, body := getBody(t, server.URL()+"/api/users/1"); status != http.StatusOK || body != "mocked user 1" {
+				t.Errorf("Expected the matching body to be returned, got %d: %s", status, body)
+			}
+
+			status, body := getBody(t, server.URL()+"/api/users/2")
+			if status != tt.expectedStatus || (tt.expectedBody != "" && body != tt.expectedBody) {
+				t.Errorf("Expected %d %s, got %d: %s", tt.expectedStatus, tt.expectedBody, status, body)
+			}
+		})
+	}
+
+	t.Run("journals the default body", func(t *testing.T) {
+		server := mockserver.New(mockserver.Options{}).Start()
+		defer server.Close()
+
+		if _, err := server.AddMock(newUserMock(model.OnNoMatchDefaultBody)); err != nil {
+			t.Fatalf("Failed to add the mock: %v", err)
+		}
+		getBody(t, server.URL()+"/api/users/2")
+
+		status, body := getBody(t, server.URL()+"/__admin/requests?path=/api/users/2")
+		var entries []map[string]any
+		if err := json.Unmarshal([]byte(body), &entries); err != nil || status != http.StatusOK || len(entries) != 1 {
+			t.Fatalf("Expected the request to be recorded, got %d: %s", status, body)
+		}
+		if entries[0]["defaultBody"] != true || entries[0]["bodyIndex"] != float64(-1) {
+			t.Errorf("Expected the default body to be recorded, got %v", entries[0])
+		}
+	})
+
+	t.Run("rejects a policy that cannot be applied", func(t *testing.T) {
+		server := mockserver.New(mockserver.Options{})
+		defer server.Close()
+
+		mock := newUserMock(model.OnNoMatchRedirect)
+		mock.Redirect.Url = ""
+		if _, err := server.AddMock(mock); err == nil {
+			t.Errorf("Expected the redirect policy without redirect URL to be rejected")
+		}
+
+		if _, err := server.AddMock(newUserMock("proxy")); err == nil {
+			t.Errorf("Expected the unknown policy to be rejected")
+		}
+	})
+}
@@ -0,0 +1,407 @@
+package mock_server
+
+import (
+	"encoding/json"
+	"github.com/softwareplace/mock-server/pkg/mockserver"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// echoedRequest is the request received by the upstream of the proxy tests.
+type echoedRequest struct {
+	Method string              `json:"method"`
+	Uri    string              `json:"uri"`
+	Header map[string][]string `json:"header"`
+	Body   string              `json:"body"`
+}
+
+func newEchoUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(echoedRequest{
+			Method: r.Method,
+			Uri:    r.URL.RequestURI(),
+			Header: r.Header,
+			Body:   string(body),
+		})
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func proxyRequest(t *testing.T, redirect *model.RedirectConfig, request *http.Request) echoedRequest {
+	t.Helper()
+
+	server := mockserver.New(mockserver.Options{
+		Config: &model.MockServerConfig{RedirectConfig: redirect},
+	}).Start()
+	defer server.Close()
+
+	target, err := http.NewRequest(request.Method, server.URL()+request.URL.RequestURI(), request.Body)
+	if err != nil {
+		t.Fatalf("Failed to create the request: %v", err)
+	}
+	target.Header = request.Header
+
+	response, err := http.DefaultClient.Do(target)
+	if err != nil {
+		t.Fatalf("Failed to request the proxy: %v", err)
+	}
+	defer response.Body.Close()
+
+	var echoed echoedRequest
+	if err := json.NewDecoder(response.Body).Decode(&echoed); err != nil {
+		t.Fatalf("Failed to decode the upstream echo: %v", err)
+	}
+	return echoed
+}
+
+func TestProxyForwardsRequests(t *testing.T) {
+	upstream := newEchoUpstream(t)
+
+	newRequest := func(method string, uri string, body string) *http.Request {
+		request := httptest.NewRequest(method, uri, strings.NewReader(body))
+		request.Header.Set("Authorization", "Bearer token")
+		request.Header.Set("Accept", "application/json")
+		request.Header.Add("Cookie", "session=1")
+		request.Header.Set("Connection", "X-Hop")
+		request.Header.Set("X-Hop", "dropped")
+		return request
+	}
+
+	t.Run("forwards the headers, query string and body", func(t *testing.T) {
+		redirect := &model.RedirectConfig{
+			Url:     upstream.URL,
+			Headers: map[string]any{"Accept": "text/plain", "X-Api-Key": 42},
+		}
+		echoed := proxyRequest(t, redirect, newRequest(http.MethodPost, "/api/orders?next=http%3A%2F%2Fexample.com//a&tag=1&tag=2", `{"sku":"SKU-1"}`))
+
+		if echoed.Method != http.MethodPost || echoed.Body != `{"sku":"SKU-1"}` {
+			t.Errorf("Expected the POST body to be forwarded, got %s %q", echoed.Method, echoed.Body)
+		}
+		if echoed.Uri != "/api/orders?next=http%3A%2F%2Fexample.com//a&tag=1&tag=2" {
+			t.Errorf("Expected the query string to be forwarded as is, got %s", echoed.Uri)
+		}
+
+		header := http.Header(echoed.Header)
+		if header.Get("Authorization") != "Bearer token" || header.Get("Cookie") != "session=1" {
+			t.Errorf("Expected the client headers to be forwarded, got %v", header)
+		}
+		if header.Get("Accept") != "text/plain" || header.Get("X-Api-Key") != "42" {
+			t.Errorf("Expected the redirect headers to take precedence, got %v", header)
+		}
+		if header.Get("X-Hop") != "" || header.Get("X-Forwarded-For") != "" {
+			t.Errorf("Expected the hop-by-hop headers to be dropped, got %v", header)
+		}
+	})
+
+	t.Run("forwards the allowed headers only", func(t *testing.T) {
+		redirect := &model.RedirectConfig{
+			Url:            upstream.URL,
+			ForwardHeaders: &model.ForwardConfig{Allow: []string{"authorization", "Cookie"}, Deny: []string{"Cookie"}},
+		}
+		header := http.Header(proxyRequest(t, redirect, newRequest(http.MethodGet, "/api/users", "")).Header)
+
+		if header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Expected the allowed header to be forwarded, got %v", header)
+		}
+		if header.Get("Cookie") != "" || header.Get("Accept") != "" {
+			t.Errorf("Expected the denied and unlisted headers to be dropped, got %v", header)
+		}
+	})
+
+	t.Run("forwards all the headers but the denied ones", func(t *testing.T) {
+		redirect := &model.RedirectConfig{
+			Url:            upstream.URL,
+			ForwardHeaders: &model.ForwardConfig{Deny: []string{"cookie"}},
+		}
+		header := http.Header(proxyRequest(t, redirect, newRequest(http.MethodGet, "/api/users", "")).Header)
+
+		if header.Get("Cookie") != "" || header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Expected the denied header only to be dropped, got %v", header)
+		}
+	})
+
+	t.Run("forwards none of the headers", func(t *testing.T) {
+		redirect := &model.RedirectConfig{
+			Url:            upstream.URL,
+			Headers:        map[string]any{"X-Api-Key": "key"},
+			ForwardHeaders: &model.ForwardConfig{Mode: model.ForwardModeNone},
+		}
+		header := http.Header(proxyRequest(t, redirect, newRequest(http.MethodGet, "/api/users", "")).Header)
+
+		if header.Get("Authorization") != "" || header.Get("X-Api-Key") != "key" {
+			t.Errorf("Expected the redirect headers only, got %v", header)
+		}
+	})
+
+	t.Run("adds the X-Forwarded headers", func(t *testing.T) {
+		redirect := &model.RedirectConfig{
+			Url:            upstream.URL,
+			ForwardHeaders: &model.ForwardConfig{XForwarded: true},
+		}
+		request := newRequest(http.MethodGet, "/api/users", "")
+		request.Header.Set("X-Forwarded-For", "203.0.113.7")
+		header := http.Header(proxyRequest(t, redirect, request).Header)
+
+		if header.Get("X-Forwarded-For") != "203.0.113.7, 127.0.0.1" {
+			t.Errorf("Expected the client address to be appended, got %q", header.Get("X-Forwarded-For"))
+		}
+		if !strings.HasPrefix(header.Get("X-Forwarded-Host"), "127.0.0.1:") || header.Get("X-Forwarded-Proto") != "http" {
+			t.Errorf("Expected the client request to be described, got %v", header)
+		}
+	})
+}
+
+func TestProxyResponses(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Add("Set-Cookie", "session=1; Path=/")
+			w.Header().Add("Set-Cookie", "theme=dark; Path=/")
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("X-Powered-By", "upstream")
+			w.Header().Set("X-Version", "1")
+			w.Header().Set("Connection", "X-Hop")
+			w.Header().Set("X-Hop", "dropped")
+			w.Header().Set("Location", "http://upstream.internal/home")
+			w.WriteHeader(http.StatusFound)
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, "data: first\n\n")
+			w.(http.Flusher).Flush()
+			<-release
+			_, _ = io.WriteString(w, "data: second\n\n")
+		}
+	}))
+	defer upstream.Close()
+
+	server := mockserver.New(mockserver.Options{
+		Config: &model.MockServerConfig{
+			RedirectConfig: &model.RedirectConfig{
+				Url:        upstream.URL,
+				LogEnabled: true,
+				ResponseHeaders: []model.HeaderRule{
+					{Name: "location", Old: "http://upstream.internal", New: "http://mock.local"},
+					{Name: "X-Powered-By", Remove: true},
+					{Name: "X-Version", Value: "2"},
+				},
+			},
+		},
+	}).Start()
+	defer server.Close()
+
+	client := &http.Client{
+		// The streamed response would otherwise wait for the release forever, if it was buffered
+		Timeout: 5 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	t.Run("copies and rewrites the response headers", func(t *testing.T) {
+		response, err := client.Get(server.URL() + "/login")
+		if err != nil {
+			t.Fatalf("Failed to request the proxy: %v", err)
+		}
+		_ = response.Body.Close()
+
+		if response.StatusCode != http.StatusFound {
+			t.Errorf("Expected the upstream redirect to be returned, got %d", response.StatusCode)
+		}
+		if cookies := response.Header.Values("Set-Cookie"); len(cookies) != 2 {
+			t.Errorf("Expected both cookies to be copied, got %v", cookies)
+		}
+		if response.Header.Get("Cache-Control") != "no-store" || response.Header.Get("ETag") != `"v1"` {
+			t.Errorf("Expected the caching headers to be copied, got %v", response.Header)
+		}
+		if response.Header.Get("Location") != "http://mock.local/home" {
+			t.Errorf("Expected the location to be rewritten, got %q", response.Header.Get("Location"))
+		}
+		if response.Header.Get("X-Powered-By") != "" || response.Header.Get("X-Version") != "2" {
+			t.Errorf("Expected the header rules to be applied, got %v", response.Header)
+		}
+		if response.Header.Get("X-Hop") != "" {
+			t.Errorf("Expected the hop-by-hop headers to be dropped, got %v", response.Header)
+		}
+	})
+
+	t.Run("streams the body as it is received", func(t *testing.T) {
+		defer func() {
+			select {
+			case <-release:
+			default:
+				close(release)
+			}
+		}()
+
+		response, err := client.Get(server.URL() + "/events")
+		if err != nil {
+			t.Fatalf("Failed to request the proxy: %v", err)
+		}
+		defer response.Body.Close()
+
+		first := make([]byte, len("data: first\n\n"))
+		if _, err := io.ReadFull(response.Body, first); err != nil || string(first) != "data: first\n\n" {
+			t.Fatalf("Expected the first event before the upstream completes, got %q: %v", first, err)
+		}
+
+		close(release)
+		rest, err := io.ReadAll(response.Body)
+		if err != nil || string(rest) != "data: second\n\n" {
+			t.Errorf("Expected the second event, got %q: %v", rest, err)
+		}
+	})
+}
+
+func TestProxyResilience(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]int)
+	hang := make(chan struct{})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		received[r.URL.Path]++
+		count := received[r.URL.Path]
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/hang":
+			<-hang
+		case "/flaky":
+			if count < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write(body)
+		case "/down":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, "data: 1\n\n")
+			w.(http.Flusher).Flush()
+			time.Sleep(300 * time.Millisecond)
+			_, _ = io.WriteString(w, "data: 2\n\n")
+		}
+	}))
+	defer upstream.Close()
+	// Released before closing the upstream, which waits for the hung request
+	defer close(hang)
+
+	receivedCount := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return received[path]
+	}
+
+	newProxy := func(redirect model.RedirectConfig) *mockserver.MockServer {
+		redirect.Url = upstream.URL
+		return mockserver.New(mockserver.Options{
+			Config: &model.MockServerConfig{RedirectConfig: &redirect},
+		}).Start()
+	}
+
+	t.Run("times out a hung upstream", func(t *testing.T) {
+		server := newProxy(model.RedirectConfig{Timeout: 100})
+		defer server.Close()
+
+		started := time.Now()
+		if status, _ := getBody(t, server.URL()+"/hang"); status != http.StatusGatewayTimeout {
+			t.Errorf("Expected the hung upstream to time out, got %d", status)
+		}
+		if elapsed := time.Since(started); elapsed > 2*time.Second {
+			t.Errorf("Expected the request to be given up after the timeout, took %v", elapsed)
+		}
+	})
+
+	t.Run("streams the idle server-sent events without explicit timeout", func(t *testing.T) {
+		server := newProxy(model.RedirectConfig{})
+		defer server.Close()
+
+		if status, body := getBody(t, server.URL()+"/events"); status != http.StatusOK || body != "data: 1\n\ndata: 2" {
+			t.Errorf("Expected every event to be streamed, got %d: %q", status, body)
+		}
+	})
+
+	t.Run("times out the idle server-sent events with an explicit timeout", func(t *testing.T) {
+		server := newProxy(model.RedirectConfig{Timeout: 100})
+		defer server.Close()
+
+		if status, body := getBody(t, server.URL()+"/events"); status != http.StatusOK || body != "data: 1" {
+			t.Errorf("Expected the stream to be cut once idle for the timeout, got %d: %q", status, body)
+		}
+	})
+
+	t.Run("retries the failed requests with their body", func(t *testing.T) {
+		server := newProxy(model.RedirectConfig{
+			Retry: &model.RetryConfig{Attempts: 2, Backoff: 10},
+			Pool:  &model.PoolConfig{MaxConnsPerHost: 1},
+		})
+		defer server.Close()
+
+		status, body := doRequest(t, http.MethodPost, server.URL()+"/flaky", `{"sku":"SKU-1"}`, "application/json")
+		if status != http.StatusOK || body != `{"sku":"SKU-1"}` {
+			t.Errorf("Expected the third attempt to succeed, got %d: %s", status, body)
+		}
+		if count := receivedCount("/flaky"); count != 3 {
+			t.Errorf("Expected 3 attempts, got %d", count)
+		}
+	})
+
+	t.Run("fails fast once the circuit is open", func(t *testing.T) {
+		server := newProxy(model.RedirectConfig{
+			CircuitBreaker: &model.BreakerConfig{
+				Threshold:    2,
+				OpenDuration: 200,
+				StatusCode:   http.StatusServiceUnavailable,
+				Body:         pointer[any](map[string]any{"message": "down"}),
+				Headers:      map[string]any{"Retry-After": 1},
+			},
+		})
+		defer server.Close()
+
+		for attempt := 0; attempt < 2; attempt++ {
+			if status, _ := getBody(t, server.URL()+"/down"); status != http.StatusInternalServerError {
+				t.Errorf("Expected the upstream failure to be returned, got %d", status)
+			}
+		}
+
+		response, err := http.Get(server.URL() + "/down")
+		if err != nil {
+			t.Fatalf("Failed to request the proxy: %v", err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if response.StatusCode != http.StatusServiceUnavailable || string(body) != `{"message":"down"}` || response.Header.Get("Retry-After") != "1" {
+			t.Errorf("Expected the configured response of the open circuit, got %d %v: %s", response.StatusCode, response.Header, body)
+		}
+		if count := receivedCount("/down"); count != 2 {
+			t.Errorf("Expected the open circuit not to reach the upstream, got %d requests", count)
+		}
+
+		time.Sleep(300 * time.Millisecond)
+		if status, _ := getBody(t, server.URL()+"/down"); status != http.StatusInternalServerError {
+			t.Errorf("Expected the upstream to be reached once the circuit is no longer open, got %d", status)
+		}
+		if status, _ := getBody(t, server.URL()+"/down"); status != http.StatusServiceUnavailable {
+			t.Errorf("Expected the circuit to open again on the next failure, got %d", status)
+		}
+	})
+}
+
+func pointer[T any](value T) *T {
+	return &value
+}
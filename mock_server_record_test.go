@@ -0,0 +1,141 @@
+package mock_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/softwareplace/mock-server/pkg/mockserver"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordMode(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/users":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Upstream", "users")
+			_, _ = fmt.Fprintf(w, `{"id":%q}`, r.URL.Query().Get("id"))
+		case "/api/orders":
+			var order map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&order)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"sku":%q,"status":"created"}`, order["sku"])
+		case "/files/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 0x50, 0x4e, 0x47, 0xff, 0x00})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	mockPath := t.TempDir()
+	recorder := mockserver.New(mockserver.Options{
+		MockPath: mockPath,
+		Config: &model.MockServerConfig{
+			RedirectConfig: &model.RedirectConfig{
+				Url:    upstream.URL,
+				Record: &model.RecordConfig{Enabled: true},
+			},
+		},
+	}).Start()
+
+	requests := []struct {
+		method      string
+		path        string
+		body        string
+		contentType string
+	}{
+		{http.MethodGet, "/api/users?id=1", "", ""},
+		{http.MethodGet, "/api/users?id=2", "", ""},
+		{http.MethodGet, "/api/users?id=1", "", ""},
+		{http.MethodPost, "/api/orders", `{"sku":"SKU-1"}`, "application/json"},
+		{http.MethodGet, "/files/logo.png", "", ""},
+	}
+	recorded := make(map[string]string)
+	for _, r := range requests {
+		status, body := doRequest(t, r.method, recorder.URL()+r.path, r.body, r.contentType)
+		recorded[r.method+r.path] = fmt.Sprintf("%d %s", status, body)
+	}
+
+	recorder.Close()
+	upstream.Close()
+
+	t.Run("merges the recordings of a route into one mock file", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join(mockPath, "get-api-users.yaml"))
+		if err != nil {
+			t.Fatalf("Failed to read the recorded mock: %v", err)
+		}
+
+		var mock model.MockConfigResponse
+		if err := yaml.Unmarshal(data, &mock); err != nil {
+			t.Fatalf("Failed to parse the recorded mock: %v", err)
+		}
+		if mock.Request.Method != http.MethodGet || mock.Request.Path != "/api/users" {
+			t.Errorf("Expected the GET /api/users request, got %+v", mock.Request)
+		}
+		if len(mock.Response.Bodies) != 2 {
+			t.Fatalf("Expected a body per query, got %d:\n%s", len(mock.Response.Bodies), data)
+		}
+		if strings.Contains(string(data), "null") || strings.Contains(string(data), "priority") {
+			t.Errorf("Expected the unused settings to be left out:\n%s", data)
+		}
+	})
+
+	t.Run("replays the recordings offline", func(t *testing.T) {
+		replay := mockserver.New(mockserver.Options{MockPath: mockPath}).Start()
+		defer replay.Close()
+
+		for _, r := range requests {
+			status, body := doRequest(t, r.method, replay.URL()+r.path, r.body, r.contentType)
+			if actual := fmt.Sprintf("%d %s", status, body); actual != recorded[r.method+r.path] {
+				t.Errorf("%s %s: expected the recorded %s, got %s", r.method, r.path, recorded[r.method+r.path], actual)
+			}
+		}
+
+		response, err := http.Get(replay.URL() + "/api/users?id=1")
+		if err != nil {
+			t.Fatalf("Failed to request the replay: %v", err)
+		}
+		_ = response.Body.Close()
+		if response.Header.Get("X-Upstream") != "users" || !strings.Contains(response.Header.Get("Content-Type"), "application/json") {
+			t.Errorf("Expected the recorded response headers, got %v", response.Header)
+		}
+
+		if status, _ := doRequest(t, http.MethodGet, replay.URL()+"/api/users?id=3", "", ""); status != http.StatusNotFound {
+			t.Errorf("Expected a query that was not recorded to be unmatched, got %d", status)
+		}
+	})
+
+}
+
+func doRequest(t *testing.T, method string, url string, body string, contentType string) (int, string) {
+	t.Helper()
+
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create the request: %v", err)
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Failed to request %s %s: %v", method, url, err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("Failed to read the response of %s %s: %v", method, url, err)
+	}
+	return response.StatusCode, string(data)
+}
@@ -0,0 +1,123 @@
+package mock_server
+
+import (
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/handler"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScenarios(t *testing.T) {
+	env.SetAppEnv(appEnv)
+
+	var appServer server.Api[*apicontext.DefaultContext]
+
+	// Load mock responses
+	handler.LoadResponses(func(restartServer bool) {
+		appServer = server.Default().
+			ContextPath(appEnv.ContextPath).
+			EmbeddedServer(handler.Register)
+	})
+
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "reset all scenarios",
+			method:         "POST",
+			path:           "/__admin/scenarios/reset",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "the cart starts empty",
+			method:         "GET",
+			path:           "/api/cart",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"items":[]}`,
+		},
+		{
+			name:           "add an item to the cart",
+			method:         "POST",
+			path:           "/api/cart/items",
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "the cart returns the added item",
+			method:         "GET",
+			path:           "/api/cart",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"items":[{"sku":"SKU-1","quantity":1}]}`,
+		},
+		{
+			name:           "the first flaky call fails",
+			method:         "GET",
+			path:           "/api/flaky/status",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "the second flaky call succeeds",
+			method:         "GET",
+			path:           "/api/flaky/status",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"UP"}`,
+		},
+		{
+			name:           "list the scenario states",
+			method:         "GET",
+			path:           "/__admin/scenarios",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"cart":"ITEM_ADDED","flaky":"RECOVERED"}`,
+		},
+		{
+			name:           "set the cart scenario state",
+			method:         "PUT",
+			path:           "/__admin/scenarios/cart/state",
+			body:           `{"state":"Started"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "the cart is empty again",
+			method:         "GET",
+			path:           "/api/cart",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"items":[]}`,
+		},
+	}
+
+	for _, step := range steps {
+		rr := serveRequest(t, appServer, step.method, step.path, step.body)
+
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("[%s] Expected status code %d, got %d", step.name, step.expectedStatus, rr.Code)
+		}
+
+		if step.expectedBody != "" && !jsonDeepEqual(rr.Body.Bytes(), []byte(step.expectedBody)) {
+			t.Fatalf("[%s] Expected body %s, got %s", step.name, step.expectedBody, rr.Body.String())
+		}
+	}
+}
+
+func serveRequest(
+	t *testing.T,
+	appServer server.Api[*apicontext.DefaultContext],
+	method string,
+	path string,
+	body string,
+) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	appServer.Router().ServeHTTP(rr, req)
+	return rr
+}
@@ -0,0 +1,158 @@
+package mock_server
+
+import (
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/handler"
+	"github.com/softwareplace/mock-server/pkg/mockserver"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestSelectionStrategies(t *testing.T) {
+	env.SetAppEnv(appEnv)
+
+	var appServer server.Api[*apicontext.DefaultContext]
+
+	// Load mock responses
+	handler.LoadResponses(func(restartServer bool) {
+		appServer = server.Default().
+			ContextPath(appEnv.ContextPath).
+			EmbeddedServer(handler.Register)
+	})
+
+	if rr := serveRequest(t, appServer, "POST", "/__admin/sequences/reset", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected status code %d resetting the sequences, got %d", http.StatusNoContent, rr.Code)
+	}
+
+	tests := []struct {
+		name             string
+		path             string
+		expectedStatuses []int
+		expectedBodies   []string
+	}{
+		{
+			name:             "sequence sticks to the last body",
+			path:             "/api/strategies/pages",
+			expectedStatuses: []int{200, 200, 200, 200},
+			expectedBodies: []string{
+				`{"page":1,"next":true}`,
+				`{"page":2,"next":true}`,
+				`{"page":3,"next":false}`,
+				`{"page":3,"next":false}`,
+			},
+		},
+		{
+			name:             "sequence with loop starts over",
+			path:             "/api/strategies/retry",
+			expectedStatuses: []int{503, 503, 200, 503},
+			expectedBodies: []string{
+				`{"attempt":1}`,
+				`{"attempt":2}`,
+				`{"attempt":3}`,
+				`{"attempt":1}`,
+			},
+		},
+		{
+			name:             "round-robin alternates the bodies",
+			path:             "/api/strategies/servers",
+			expectedStatuses: []int{200, 200, 200},
+			expectedBodies: []string{
+				`{"server":"A"}`,
+				`{"server":"B"}`,
+				`{"server":"A"}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for index, expectedBody := range tt.expectedBodies {
+				rr := serveRequest(t, appServer, "GET", tt.path, "")
+
+				if rr.Code != tt.expectedStatuses[index] {
+					t.Errorf("Call #%d expected status code %d, got %d", index, tt.expectedStatuses[index], rr.Code)
+				}
+
+				if !jsonDeepEqual(rr.Body.Bytes(), []byte(expectedBody)) {
+					t.Errorf("Call #%d expected body %s, got %s", index, expectedBody, rr.Body.String())
+				}
+			}
+		})
+	}
+
+}
+
+func TestWeightedRandomSelection(t *testing.T) {
+	newCoinServer := func() *mockserver.MockServer {
+		server := mockserver.New(mockserver.Options{Config: &model.MockServerConfig{Seed: 42}}).Start()
+
+		var heads, tails any = "heads", "tails"
+		_, err := server.AddMock(model.MockConfigResponse{
+			Request: model.RequestConfig{Method: http.MethodGet, Path: "/api/coin"},
+			Response: model.ResponseConfig{
+				ContentType: "text/plain",
+				Strategy:    model.StrategyWeightedRandom,
+				Bodies: []model.ResponseBody{
+					{Body: &heads, Weight: 3},
+					{Body: &tails, Weight: 1},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to add the mock: %v", err)
+		}
+		return server
+	}
+
+	flip := func(server *mockserver.MockServer, times int) []string {
+		var sides []string
+		for range times {
+			_, body := getBody(t, server.URL()+"/api/coin")
+			sides = append(sides, body)
+		}
+		return sides
+	}
+
+	server := newCoinServer()
+	defer server.Close()
+
+	sides := flip(server, 400)
+	counts := map[string]int{}
+	for _, side := range sides {
+		counts[side]++
+	}
+
+	t.Run("returns the bodies proportionally to their weight", func(t *testing.T) {
+		if len(counts) != 2 {
+			t.Fatalf("Expected only both bodies to be returned, got %v", counts)
+		}
+		if ratio := float64(counts["heads"]) / float64(len(sides)); ratio < 0.68 || ratio > 0.82 {
+			t.Errorf("Expected about 3 heads for 1 tails, got %v", counts)
+		}
+	})
+
+	t.Run("is reproducible with the same seed", func(t *testing.T) {
+		other := newCoinServer()
+		defer other.Close()
+
+		if !slices.Equal(sides, flip(other, len(sides))) {
+			t.Errorf("Expected the same seed to return the same bodies")
+		}
+	})
+
+	t.Run("starts over on reset", func(t *testing.T) {
+		rr, err := http.Post(server.URL()+"/__admin/sequences/reset", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Failed to reset the sequences: %v", err)
+		}
+		_ = rr.Body.Close()
+
+		if !slices.Equal(sides, flip(server, len(sides))) {
+			t.Errorf("Expected the reset to start over from the seed")
+		}
+	})
+}
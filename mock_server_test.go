@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -41,13 +42,16 @@ func TestMockServer(t *testing.T) {
 
 	// Test cases
 	tests := []struct {
-		name           string
-		method         string
-		path           string
-		queryParams    map[string]string
-		headers        map[string]string
-		expectedStatus int
-		expectedBody   string
+		name            string
+		method          string
+		path            string
+		queryParams     map[string]string
+		headers         map[string]string
+		body            string
+		expectedStatus  int
+		expectedBody    string
+		expectedRawBody string
+		expectedHeaders map[string]string
 	}{
 		{
 			name:           "Test GET /api/products/1",
@@ -89,12 +93,271 @@ func TestMockServer(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"id":3,"name":"User For Queries request","email":"john.doe+3@email.com"}`,
 		},
+		{
+			name:           "Test GET /api/template/user/7 renders the path value",
+			method:         "GET",
+			path:           "/api/template/user/7",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":"7","name":"User 7","email":"john.doe+7@email.com","locale":"en"}`,
+		},
+		{
+			name:           "Test GET /api/template/user/8 renders the query value",
+			method:         "GET",
+			path:           "/api/template/user/8",
+			queryParams:    map[string]string{"locale": "pt-BR"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":"8","name":"User 8","email":"john.doe+8@email.com","locale":"pt-BR"}`,
+		},
+		{
+			name:           "Test GET /api/orders/150 matches the id range with authorization present",
+			method:         "GET",
+			path:           "/api/orders/150",
+			headers:        map[string]string{"Authorization": "Bearer token"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":100,"status":"AUTHORIZED"}`,
+		},
+		{
+			name:           "Test GET /api/orders/150 without authorization is not found",
+			method:         "GET",
+			path:           "/api/orders/150",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Test GET /api/orders/abc matches the id regex",
+			method:         "GET",
+			path:           "/api/orders/abc",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":"slug","status":"BY_SLUG"}`,
+		},
+		{
+			name:           "Test GET /api/orders/8 matches one of the ids",
+			method:         "GET",
+			path:           "/api/orders/8",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":7,"status":"ONE_OF"}`,
+		},
+		{
+			name:           "Test GET /api/orders/8 with debug query is not found",
+			method:         "GET",
+			path:           "/api/orders/8",
+			queryParams:    map[string]string{"debug": "true"},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Test GET /api/orders/9 with the strict queries and the debug query not present",
+			method:         "GET",
+			path:           "/api/orders/9",
+			queryParams:    map[string]string{"format": "short"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":9,"status":"SHORT"}`,
+		},
+		{
+			name:           "Test GET /api/orders/9 with the debug query is not found",
+			method:         "GET",
+			path:           "/api/orders/9",
+			queryParams:    map[string]string{"format": "short", "debug": "true"},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Test POST /api/orders with the exact body",
+			method:         "POST",
+			path:           "/api/orders",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"customer":{"id":1},"items":[{"sku":"SKU-1","quantity":1}]}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":1,"status":"EXACT"}`,
+		},
+		{
+			name:           "Test POST /api/orders matching the JSONPath expressions",
+			method:         "POST",
+			path:           "/api/orders",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"customer":{"id":1,"type":"VIP"},"items":[{"sku":"SKU-1"},{"sku":"SKU-2"}]}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":2,"status":"VIP"}`,
+		},
+		{
+			name:           "Test POST /api/orders matching the partial body",
+			method:         "POST",
+			path:           "/api/orders",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"customer":{"id":42,"name":"John"},"items":[]}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":42,"status":"PARTIAL"}`,
+		},
+		{
+			name:           "Test POST /api/orders with an unknown body is not found",
+			method:         "POST",
+			path:           "/api/orders",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"customer":{"id":7}}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Test POST /api/orders/form matching the form fields",
+			method:         "POST",
+			path:           "/api/orders/form",
+			headers:        map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:           "sku=SKU-1&quantity=3",
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":1,"status":"FORM"}`,
+		},
+		{
+			name:           "Test POST /api/orders/form matching the raw body regex",
+			method:         "POST",
+			path:           "/api/orders/form",
+			headers:        map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:           "note=very+urgent",
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":2,"status":"URGENT"}`,
+		},
+		{
+			name:           "Test GET /v1/products/search ignores the cache buster query",
+			method:         "GET",
+			path:           "/v1/products/search",
+			queryParams:    map[string]string{"id": "1", "_t": "123"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"id":1,"name":"Product 1"}]`,
+		},
+		{
+			name:           "Test GET /v1/products/search with an unexpected query is not found",
+			method:         "GET",
+			path:           "/v1/products/search",
+			queryParams:    map[string]string{"id": "1", "page": "2"},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Test GET /v1/products/search matches the subset of queries",
+			method:         "GET",
+			path:           "/v1/products/search",
+			queryParams:    map[string]string{"category": "books", "page": "2", "size": "10"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"id":3,"name":"Book 1"}]`,
+		},
+		{
+			name:           "Test GET /v1/products/tags matches the exact list of values",
+			method:         "GET",
+			path:           "/v1/products/tags?tag=new&tag=sale",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"match":"EXACT"}`,
+		},
+		{
+			name:           "Test GET /v1/products/tags matches all of the values",
+			method:         "GET",
+			path:           "/v1/products/tags?tag=kids&tag=new&tag=books",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"match":"ALL_OF"}`,
+		},
+		{
+			name:           "Test GET /v1/products/tags matches any of the values",
+			method:         "GET",
+			path:           "/v1/products/tags?tag=sale&tag=games",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"match":"ANY_OF"}`,
+		},
+		{
+			name:           "Test GET /v1/products/tags with values out of order is not found",
+			method:         "GET",
+			path:           "/v1/products/tags?tag=sale&tag=new",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Test GET /v1/products/1/details picks the most specific body",
+			method:         "GET",
+			path:           "/v1/products/1/details",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"match":"EXACT"}`,
+		},
+		{
+			name:           "Test GET /v1/products/42/details picks the matching body over the default",
+			method:         "GET",
+			path:           "/v1/products/42/details",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"match":"NUMERIC"}`,
+		},
+		{
+			name:           "Test GET /v1/products/abc/details falls back to the body without matching",
+			method:         "GET",
+			path:           "/v1/products/abc/details",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"match":"DEFAULT"}`,
+		},
+		{
+			name:           "Test GET /v1/products/9/details picks the body with the highest priority",
+			method:         "GET",
+			path:           "/v1/products/9/details",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"match":"PRIORITY"}`,
+		},
+		{
+			name:            "Test GET /api/orders/1/status uses the response status code",
+			method:          "GET",
+			path:            "/api/orders/1/status",
+			expectedStatus:  http.StatusOK,
+			expectedBody:    `{"id":1,"status":"DELIVERED"}`,
+			expectedHeaders: map[string]string{"Content-Type": "application/json"},
+		},
+		{
+			name:            "Test GET /api/orders/999/status uses the body status code and content type",
+			method:          "GET",
+			path:            "/api/orders/999/status",
+			expectedStatus:  http.StatusNotFound,
+			expectedBody:    `{"title":"Order not found","status":404}`,
+			expectedHeaders: map[string]string{"Content-Type": "application/problem+json"},
+		},
+		{
+			name:            "Test GET /api/content/text writes the plain string",
+			method:          "GET",
+			path:            "/api/content/text",
+			expectedStatus:  http.StatusOK,
+			expectedRawBody: "Hello, World!",
+			expectedHeaders: map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		},
+		{
+			name:            "Test GET /api/content/xml writes the XML document",
+			method:          "GET",
+			path:            "/api/content/xml",
+			expectedStatus:  http.StatusOK,
+			expectedRawBody: "<product>\n  <id>1</id>\n  <name>Product 1</name>\n</product>\n",
+			expectedHeaders: map[string]string{"Content-Type": "application/xml"},
+		},
+		{
+			name:            "Test GET /api/content/csv writes the CSV content",
+			method:          "GET",
+			path:            "/api/content/csv",
+			expectedStatus:  http.StatusOK,
+			expectedRawBody: "id,name,amount\n1,Product 1,2500.75\n2,Product 2,2500.75\n",
+			expectedHeaders: map[string]string{"Content-Type": "text/csv"},
+		},
+		{
+			name:            "Test GET /api/content/gif decodes the base64 body",
+			method:          "GET",
+			path:            "/api/content/gif",
+			expectedStatus:  http.StatusOK,
+			expectedRawBody: "GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\xff\xff\xff!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x01D\x00;",
+			expectedHeaders: map[string]string{"Content-Type": "image/gif", "Content-Length": "42"},
+		},
+		{
+			name:            "Test GET /api/files/report streams the body file",
+			method:          "GET",
+			path:            "/api/files/report",
+			expectedStatus:  http.StatusOK,
+			expectedRawBody: "id,name,amount\n1,Product 1,2500.75\n2,Product 2,2500.75\n3,Product 3,2500.75\n",
+			expectedHeaders: map[string]string{"Content-Length": "75", "Content-Disposition": "attachment; filename=report.csv"},
+		},
+		{
+			name:            "Test GET /api/files/pixel streams the binary body file with its content type",
+			method:          "GET",
+			path:            "/api/files/pixel",
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Content-Type": "image/gif", "Content-Length": "42"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create a new request
-			req, err := http.NewRequest(tt.method, tt.path, nil)
+			req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
 			if err != nil {
 				t.Fatalf("Failed to create request: %v", err)
 			}
@@ -122,6 +385,18 @@ func TestMockServer(t *testing.T) {
 				t.Errorf("[%s] Expected status code %d, got %d", tt.name, tt.expectedStatus, rr.Code)
 			}
 
+			// Check the response headers
+			for key, value := range tt.expectedHeaders {
+				if rr.Header().Get(key) != value {
+					t.Errorf("[%s] Expected header %s to be %q, got %q", tt.name, key, value, rr.Header().Get(key))
+				}
+			}
+
+			// Check the raw response body
+			if tt.expectedRawBody != "" && rr.Body.String() != tt.expectedRawBody {
+				t.Errorf("[%s] Expected raw body %q, got %q", tt.name, tt.expectedRawBody, rr.Body.String())
+			}
+
 			// Check the response body
 			if tt.expectedBody != "" {
 				responseBody, err := io.ReadAll(rr.Body)
@@ -6,19 +6,21 @@ import (
 	"github.com/softwareplace/mock-server/pkg/model"
 )
 
-func Load(configFilePath string) {
+// Load returns the configuration of the file, or nil when no file is provided or it cannot be loaded.
+func Load(configFilePath string) *model.MockServerConfig {
 	if configFilePath != "" {
 
 		config, err := file.FromYaml(configFilePath, model.MockServerConfig{})
 		if err != nil {
 			log.Errorf("Failed to load config file: %v", err)
-			return
+			return nil
 		}
 
-		model.Config = config
+		return config
 	}
+	return nil
 }
 
-func HasAValidRedirectConfig() bool {
-	return model.Config != nil && model.Config.RedirectConfig != nil && model.Config.RedirectConfig.Url != ""
+func HasAValidRedirectConfig(config *model.MockServerConfig) bool {
+	return config != nil && config.RedirectConfig != nil && config.RedirectConfig.Url != ""
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/softwareplace/mock-server/pkg/model"
 	"os"
 	"strings"
+	"sync"
 )
 
 type AppEnv struct {
@@ -14,11 +15,19 @@ type AppEnv struct {
 	MockPath     string
 	ContextPath  string
 	ServerConfig string
+	Debug        bool                    // Debug answers the unmatched requests with the mocks closest to them and why they do not match.
+	Config       *model.MockServerConfig // Config is the content of the ServerConfig file, nil when none is provided.
 }
 
-var env *AppEnv
+var (
+	env   *AppEnv
+	envMu sync.Mutex
+)
 
 func SetAppEnv(appEnv *AppEnv) {
+	envMu.Lock()
+	defer envMu.Unlock()
+
 	env = appEnv
 }
 
@@ -42,29 +51,36 @@ func UserHomePathFix(path string) string {
 }
 
 func GetAppEnv() *AppEnv {
+	envMu.Lock()
+	defer envMu.Unlock()
+
 	if env == nil {
 		serverConfig := flag.String("config", "", "The configuration file to use for the mock server")
 		mockPath := flag.String("mock", "", "Directory path containing JSON files")
 		contextPath := flag.String("context-path", "/", "The context path to use for the mock server")
 		portFlag := flag.String("port", "8080", "Port to run the mock server on")
+		debug := flag.Bool("debug", false, "Answer the unmatched requests with the closest mocks and why they do not match")
 
 		flag.Parse()
 
-		config.Load(*serverConfig)
+		serverConfigFile := config.Load(*serverConfig)
 
-		if model.Config != nil {
-			if model.Config.MockPath != "" {
-				*mockPath = model.Config.MockPath
+		if serverConfigFile != nil {
+			if serverConfigFile.MockPath != "" {
+				*mockPath = serverConfigFile.MockPath
+			}
+			if serverConfigFile.ContextPath != "" {
+				*contextPath = serverConfigFile.ContextPath
 			}
-			if model.Config.ContextPath != "" {
-				*contextPath = model.Config.ContextPath
+			if serverConfigFile.Port != "" {
+				*portFlag = serverConfigFile.Port
 			}
-			if model.Config.Port != "" {
-				*portFlag = model.Config.Port
+			if serverConfigFile.Debug {
+				*debug = true
 			}
 
-			if model.Config.RedirectConfig != nil {
-				model.Config.RedirectConfig.StoreResponsesDir = UserHomePathFix(model.Config.RedirectConfig.StoreResponsesDir)
+			if serverConfigFile.RedirectConfig != nil {
+				serverConfigFile.RedirectConfig.StoreResponsesDir = UserHomePathFix(serverConfigFile.RedirectConfig.StoreResponsesDir)
 			}
 		}
 
@@ -85,6 +101,8 @@ func GetAppEnv() *AppEnv {
 			MockPath:     *mockPath,
 			ContextPath:  strings.TrimSuffix(*contextPath, "/") + "/",
 			ServerConfig: *serverConfig,
+			Debug:        *debug,
+			Config:       serverConfigFile,
 		}
 	}
 	return env
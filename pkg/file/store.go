@@ -23,3 +23,22 @@ func SaveToFile(data []byte, filePath string) error {
 
 	return nil
 }
+
+// CreateFile works as SaveToFile, but never overwrites an existing file,
+// failing with an error matching os.ErrExist instead.
+func CreateFile(data []byte, filePath string) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+)
+
+// AdminPath is the base path, relative to the context path, of the endpoints used to manage the mock server at runtime.
+const AdminPath = "/__admin"
+
+func (s *Server) registerAdminHandlers(appServer server.Api[*apicontext.DefaultContext]) {
+	s.registerScenarioHandlers(appServer)
+	s.registerSelectionHandlers(appServer)
+	s.registerChaosHandlers(appServer)
+	s.registerMockHandlers(appServer)
+	s.registerJournalHandlers(appServer)
+}
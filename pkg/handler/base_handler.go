@@ -7,24 +7,38 @@ import (
 	"github.com/softwareplace/goserve/server"
 	"github.com/softwareplace/mock-server/pkg/env"
 	"github.com/softwareplace/mock-server/pkg/model"
+	"mime"
 	"net/http"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
 
-func Register(appServer server.Api[*apicontext.DefaultContext]) {
-	for _, config := range model.MockConfigResponses {
+// Register registers the admin endpoints and a handler for each of the current mocks.
+func (s *Server) Register(appServer server.Api[*apicontext.DefaultContext]) {
+	s.register(appServer, s.Mocks())
+}
+
+func (s *Server) register(appServer server.Api[*apicontext.DefaultContext], mocks []model.MockConfigResponse) {
+	s.registerAdminHandlers(appServer)
+
+	for _, config := range mocks {
 		if config.Request.Method != "" && config.Request.Path != "" {
 			if config.Redirect.Url != "" || config.Response.Bodies != nil {
-				contextPath := env.GetAppEnv().ContextPath
+				contextPath := s.env.ContextPath
 				path := strings.TrimPrefix(config.Request.Path, "/")
 				log.Infof("Registering handler for %s::%s%s", config.Request.Method, contextPath, path)
 
 				appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
 					url := ctx.Request.RequestURI
 					log.Infof("Request %s::%s", config.Request.Method, url)
-					if !redirectHandler(ctx, config) {
-						requestHandler(ctx, config)
+					defer s.journalRequest(ctx, &config)()
+					if s.applyChaos(ctx) {
+						return
+					}
+					if !s.redirectHandler(ctx, config) {
+						s.requestHandler(ctx, config)
 					}
 
 				}, config.Request.Path, config.Request.Method)
@@ -36,121 +50,302 @@ func Register(appServer server.Api[*apicontext.DefaultContext]) {
 	}
 }
 
-func redirectHandler(ctx *apicontext.Request[*apicontext.DefaultContext], config model.MockConfigResponse) (redirected bool) {
+func (s *Server) redirectHandler(ctx *apicontext.Request[*apicontext.DefaultContext], config model.MockConfigResponse) (redirected bool) {
+	// With the OnNoMatchRedirect policy, only the requests none of the bodies matches are redirected
+	if config.Response.OnNoMatch == model.OnNoMatchRedirect && len(config.Response.Bodies) > 0 {
+		return false
+	}
 	if config.Redirect.Url != "" {
-		return requestRedirectHandler(ctx, config.Redirect)
+		return s.requestRedirectHandler(ctx, config.Redirect)
 
 	}
 	return false
 }
 
-func requestHandler(
+func (s *Server) requestHandler(
 	ctx *apicontext.Request[*apicontext.DefaultContext],
 	config model.MockConfigResponse,
 ) {
 	bodies := config.Response.Bodies
-	var matchedBody *model.ResponseBody
-
-	matchedBody = findMatchingBody(ctx, bodies)
+	matchedBody, matches := s.findMatchingBody(ctx, config)
 
-	writer := *ctx.Writer
-
-	// If no matching body is found, use the first body as a default
+	// If no matching body is found, apply the policy of the mock
 	if matchedBody == nil && len(bodies) > 0 {
-		writer.WriteHeader(http.StatusNotFound)
-		_, _ = writer.Write([]byte("Resource not found"))
+		s.writeOnNoMatch(ctx, config, matches)
 		return
 	}
 
 	// If a matching body is found, return it as the response
 	if matchedBody != nil {
-		if matchedBody.Headers != nil {
-			for key, value := range *matchedBody.Headers {
-				writer.Header().Set(key, fmt.Sprintf("%v", value))
-			}
+		journalMatchedBody(ctx, config, matchedBody)
+		s.writeResponseBody(ctx, config, matchedBody)
+		return
+	}
+
+	ctx.Error("Resource not found", http.StatusNotFound)
+}
+
+// writeResponseBody writes the body of the mock as the response, applying its templates, headers, delay and fault.
+func (s *Server) writeResponseBody(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	config model.MockConfigResponse,
+	matchedBody *model.ResponseBody,
+) {
+	writer := *ctx.Writer
+	body, headers := matchedBody.Body, matchedBody.Headers
+
+	if config.Response.Template {
+		var err error
+		body, headers, err = renderTemplates(ctx, body, headers)
+		if err != nil {
+			log.Errorf("Failed to render response template of %s: %v", config.MockFilePath, err)
+			ctx.Error("Failed to render response template", http.StatusInternalServerError)
+			return
 		}
+	}
+
+	if contentType := resolveContentType(config, matchedBody); contentType != "" {
+		writer.Header().Set("Content-Type", contentType)
+	}
 
-		if config.Response.Delay > 0 {
-			time.Sleep(time.Duration(config.Response.Delay) * time.Millisecond)
+	if headers != nil {
+		for key, value := range *headers {
+			writer.Header().Set(key, fmt.Sprintf("%v", value))
 		}
+	}
+
+	if wait := s.resolveWait(config, matchedBody); wait > 0 {
+		time.Sleep(wait)
+	}
 
-		ctx.Response(matchedBody.Body, config.Response.StatusCode)
+	s.moveScenario(matchedBody)
+
+	if fault := resolveFault(config, matchedBody); fault != "" {
+		s.writeFault(ctx, config, body, matchedBody, fault)
 		return
 	}
 
-	ctx.Error("Resource not found", http.StatusNotFound)
+	bytesPerSecond := s.resolveBytesPerSecond(config, matchedBody)
+	if matchedBody.BodyFile != "" {
+		writeBodyFile(ctx, s.resolveBodyFile(config, matchedBody), resolveStatusCode(config, matchedBody), bytesPerSecond)
+		return
+	}
+
+	writeBody(ctx, body, resolveStatusCode(config, matchedBody), matchedBody.Encoding, bytesPerSecond)
+}
+
+// resolveStatusCode returns the status code of the body, falling back to the one of the response.
+func resolveStatusCode(config model.MockConfigResponse, body *model.ResponseBody) int {
+	if body.StatusCode != 0 {
+		return body.StatusCode
+	}
+	if config.Response.StatusCode != 0 {
+		return config.Response.StatusCode
+	}
+	return http.StatusOK
+}
+
+// resolveContentType returns the content type of the body, then the one of its body file extension,
+// falling back to the one of the response.
+func resolveContentType(config model.MockConfigResponse, body *model.ResponseBody) string {
+	if body.ContentType != "" {
+		return body.ContentType
+	}
+	if body.BodyFile != "" {
+		if contentType := mime.TypeByExtension(filepath.Ext(body.BodyFile)); contentType != "" {
+			return contentType
+		}
+	}
+	return config.Response.ContentType
+}
+
+// resolveBodyFile returns the path of the body file, resolved relative to the mock file directory.
+func (s *Server) resolveBodyFile(config model.MockConfigResponse, body *model.ResponseBody) string {
+	bodyFile := env.UserHomePathFix(body.BodyFile)
+	if filepath.IsAbs(bodyFile) {
+		return bodyFile
+	}
+	if config.MockFilePath == "" {
+		// Mocks created through the admin endpoints have no file, their body files are relative to the mock directory
+		return filepath.Join(s.env.MockPath, bodyFile)
+	}
+	return filepath.Join(filepath.Dir(config.MockFilePath), bodyFile)
+}
+
+// bodyMatch is the result of evaluating the matching criteria of a response body against the request.
+type bodyMatch struct {
+	index int                 // index is the position of the body in the mock file.
+	body  *model.ResponseBody // body is the evaluated response body.
+	score int                 // score is the specificity of the satisfied matchers, the higher the more specific.
+	err   error               // err describes why the body does not match the request, nil when it does.
 }
 
-func findMatchingBody(
+// findMatchingBody evaluates every body against the request and returns the best match, which is the
+// one with the highest priority, then the highest score. The first body in the file wins a tie.
+// When the response defines a selection strategy, the body is chosen by it among the matching ones instead.
+// The evaluation of every body is returned as well, to explain why none matches.
+func (s *Server) findMatchingBody(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	config model.MockConfigResponse,
+) (*model.ResponseBody, []bodyMatch) {
+	matches := s.evaluateBodies(ctx, config.Response.Bodies)
+
+	if config.Response.Strategy != "" && config.Response.Strategy != model.StrategyBestMatch {
+		return s.selectBody(config, matches), matches
+	}
+
+	var best *bodyMatch
+
+	for index := range matches {
+		match := &matches[index]
+		if match.err == nil && (best == nil || isBetterMatch(match, best)) {
+			best = match
+		}
+	}
+
+	if best == nil {
+		return nil, matches
+	}
+
+	// The debug mode explains the choice, like it explains the unmatched requests
+	if s.env.Debug {
+		log.Infof("Body #%d won for request %s with priority %d and score %d%s",
+			best.index, ctx.Request.URL.RequestURI(), best.body.Priority, best.score, describeLosers(matches, best))
+	}
+	return best.body, matches
+}
+
+func (s *Server) evaluateBodies(
 	ctx *apicontext.Request[*apicontext.DefaultContext],
 	bodies []model.ResponseBody,
-) *model.ResponseBody {
-	var matchedBody *model.ResponseBody
-	// Extract query and path parameters from the incoming request
-
-	// Iterate through the bodies to find a match
-	for _, body := range bodies {
-		if containsExpectedPathsAndQueries(ctx, body) {
-			matchedBody = &body
-			break
+) []bodyMatch {
+	matches := make([]bodyMatch, len(bodies))
+	for index := range bodies {
+		body := &bodies[index]
+		score, err := s.matchBodyCriteria(ctx, *body)
+		matches[index] = bodyMatch{index: index, body: body, score: score, err: err}
+	}
+	return matches
+}
+
+func isBetterMatch(match *bodyMatch, current *bodyMatch) bool {
+	if match.body.Priority != current.body.Priority {
+		return match.body.Priority > current.body.Priority
+	}
+	return match.score > current.score
+}
+
+func describeLosers(matches []bodyMatch, best *bodyMatch) string {
+	var reasons []string
+	for _, match := range matches {
+		switch {
+		case match.index == best.index:
+			continue
+		case match.err != nil:
+			reasons = append(reasons, fmt.Sprintf("body #%d did not match: %v", match.index, match.err))
+		default:
+			reasons = append(reasons, fmt.Sprintf("body #%d matched with priority %d and score %d",
+				match.index, match.body.Priority, match.score))
 		}
 	}
-	return matchedBody
+
+	if len(reasons) == 0 {
+		return ""
+	}
+	return "; " + strings.Join(reasons, "; ")
 }
 
-func containsExpectedPathsAndQueries(
+// matchBodyCriteria checks the matching criteria of the body against the request,
+// returning the score of the satisfied matchers or an error describing the first mismatch.
+func (s *Server) matchBodyCriteria(
 	ctx *apicontext.Request[*apicontext.DefaultContext],
 	body model.ResponseBody,
-) bool {
+) (int, error) {
+	score, err := s.matchScenario(body)
+	if err != nil {
+		return 0, err
+	}
+
 	if body.Matching == nil {
-		return true
+		return score, nil
 	}
 
-	return containsExpectedPaths(ctx, body) &&
-		containsExpectedQueries(ctx, body) &&
-		containsExpectedHeaders(ctx, body)
+	for _, matcher := range []func(*apicontext.Request[*apicontext.DefaultContext], model.Matching) (int, error){
+		matchPaths,
+		matchQueries,
+		matchHeaders,
+		matchRequestBody,
+	} {
+		matcherScore, err := matcher(ctx, *body.Matching)
+		if err != nil {
+			return 0, err
+		}
+		score += matcherScore
+	}
+	return score, nil
 }
 
-func containsExpectedPaths(
+func matchPaths(
 	ctx *apicontext.Request[*apicontext.DefaultContext],
-	body model.ResponseBody,
-) bool {
-	requestedPaths := ctx.PathValues
-	// Check if the paths match
-	pathsMatch := len(requestedPaths) == len(body.Matching.Paths)
-	for key, value := range body.Matching.Paths {
-		if requestedPaths[key] != fmt.Sprintf("%v", value) {
-			pathsMatch = false
-			break
+	matching model.Matching,
+) (int, error) {
+	requestedPaths := make(map[string]string)
+	for key, value := range ctx.PathValues {
+		if !slices.Contains(matching.IgnorePaths, key) {
+			requestedPaths[key] = value
 		}
 	}
-	if pathsMatch {
-		log.Infof("Paths match for request %s", ctx.Request.URL.RequestURI())
+
+	expected := expectedCount(matching.Paths)
+	if matching.PathMode != model.MatchingModeSubset && len(requestedPaths) != expected {
+		return 0, fmt.Errorf("expected %d path values but got %d", expected, len(requestedPaths))
 	}
-	return pathsMatch
-}
 
-func containsExpectedQueries(ctx *apicontext.Request[*apicontext.DefaultContext], body model.ResponseBody) bool {
-	requestedQueries := ctx.QueryValues
-	var queriesMatch = len(requestedQueries) == len(body.Matching.Queries)
-	for key, value := range body.Matching.Queries {
-		if len(requestedQueries[key]) == 0 {
-			queriesMatch = false
-			break
+	score := 0
+	for key, value := range matching.Paths {
+		var values []string
+		if requestedPath, found := requestedPaths[key]; found {
+			values = []string{requestedPath}
+		}
+
+		if !matchValue(value, values) {
+			return 0, mismatchError("path value", key, value, values)
 		}
+		score += matcherScore(value)
+	}
+	return score, nil
+}
 
-		if requestedQueries[key][0] != fmt.Sprintf("%v", value) {
-			queriesMatch = false
-			break
+func matchQueries(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	matching model.Matching,
+) (int, error) {
+	requestedQueries := make(map[string][]string)
+	for key, values := range ctx.QueryValues {
+		if !slices.Contains(matching.IgnoreQueries, key) {
+			requestedQueries[key] = values
 		}
 	}
-	if queriesMatch {
-		log.Infof("Queries match for request %s", ctx.Request.URL.RequestURI())
+
+	expected := expectedCount(matching.Queries)
+	if matching.QueryMode != model.MatchingModeSubset && len(requestedQueries) != expected {
+		return 0, fmt.Errorf("expected %d query parameters but got %d", expected, len(requestedQueries))
+	}
+
+	score := 0
+	for key, value := range matching.Queries {
+		if !matchValue(value, requestedQueries[key]) {
+			return 0, mismatchError("query", key, value, requestedQueries[key])
+		}
+		score += matcherScore(value)
 	}
-	return queriesMatch
+	return score, nil
 }
 
-func containsExpectedHeaders(ctx *apicontext.Request[*apicontext.DefaultContext], body model.ResponseBody) bool {
+func matchHeaders(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	matching model.Matching,
+) (int, error) {
 	var requestHeaders = make(map[string][]string)
 
 	if ctx.Request.Header != nil {
@@ -161,20 +356,23 @@ func containsExpectedHeaders(ctx *apicontext.Request[*apicontext.DefaultContext]
 		}
 	}
 
-	var headersMatch = true
-
-	for key, value := range body.Matching.Headers {
-		if len(requestHeaders[key]) == 0 {
-			headersMatch = false
-			break
-		}
-		if requestHeaders[key][0] != fmt.Sprintf("%v", value) {
-			headersMatch = false
-			break
+	score := 0
+	for key, value := range matching.Headers {
+		values := requestHeaders[strings.ToLower(key)]
+		if !matchValue(value, values) {
+			return 0, mismatchError("header", key, value, values)
 		}
+		score += matcherScore(value)
+	}
+	return score, nil
+}
+
+func mismatchError(kind string, key string, expected any, values []string) error {
+	if len(values) == 0 {
+		return fmt.Errorf("%s %q is missing, expected %v", kind, key, expected)
 	}
-	if headersMatch {
-		log.Infof("Headers match for request %s", ctx.Request.URL.RequestURI())
+	if len(values) == 1 {
+		return fmt.Errorf("%s %q is %q, expected %v", kind, key, values[0], expected)
 	}
-	return headersMatch
+	return fmt.Errorf("%s %q is %q, expected %v", kind, key, values, expected)
 }
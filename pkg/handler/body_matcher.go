@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+func matchRequestBody(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	matching model.Matching,
+) (int, error) {
+	if matching.Body == nil {
+		return 0, nil
+	}
+	return matchBody(*matching.Body, readRequestBody(ctx))
+}
+
+// matchBody checks if the request payload satisfies every criteria of the body matching,
+// returning the score of the satisfied criteria or an error describing the first mismatch.
+func matchBody(expected model.BodyMatching, data []byte) (int, error) {
+	score := 0
+
+	if expected.Regex != "" {
+		regex, err := compileRegex(expected.Regex)
+		if err != nil {
+			return 0, fmt.Errorf("invalid body regex %q: %w", expected.Regex, err)
+		}
+		if !regex.MatchString(string(data)) {
+			return 0, fmt.Errorf("body does not match the regex %q", expected.Regex)
+		}
+		score += scoreOperator
+	}
+
+	if len(expected.Form) > 0 {
+		form, err := url.ParseQuery(string(data))
+		if err != nil {
+			return 0, fmt.Errorf("body is not a valid form: %w", err)
+		}
+		for key, value := range expected.Form {
+			if !matchValue(value, form[key]) {
+				return 0, mismatchError("form field", key, value, form[key])
+			}
+			score += matcherScore(value)
+		}
+	}
+
+	if expected.EqualTo == nil && expected.Partial == nil && len(expected.JsonPath) == 0 {
+		return score, nil
+	}
+
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return 0, fmt.Errorf("body is not a valid JSON: %w", err)
+	}
+
+	if expected.EqualTo != nil {
+		if !reflect.DeepEqual(normalizeJson(expected.EqualTo), document) {
+			return 0, fmt.Errorf("body is not equal to the expected document")
+		}
+		score += scoreExact
+	}
+
+	if expected.Partial != nil {
+		if !isJsonSubset(normalizeJson(expected.Partial), document) {
+			return 0, fmt.Errorf("body does not contain the expected partial document")
+		}
+		score += scoreOperator
+	}
+
+	for expression, value := range expected.JsonPath {
+		found, err := evaluateJsonPath(expression, document)
+		if err != nil {
+			return 0, err
+		}
+		if !matchJsonPathValues(value, found) {
+			return 0, fmt.Errorf("body JSONPath %q found %v, expected %v", expression, found, value)
+		}
+		score += matcherScore(value)
+	}
+
+	return score, nil
+}
+
+// matchJsonPathValues checks if any of the values found by a JSONPath expression satisfies the expected matching value.
+func matchJsonPathValues(expected any, found []any) bool {
+	if len(found) == 0 {
+		return matchValue(expected, nil)
+	}
+
+	for _, value := range found {
+		if matchValue(expected, []string{stringifyJson(value)}) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJsonSubset checks if every field of the expected document is part of the actual one. Arrays
+// match when every expected item is a subset of any actual item, regardless of the order.
+func isJsonSubset(expected any, actual any) bool {
+	switch typed := expected.(type) {
+	case map[string]any:
+		actualMap, isMap := actual.(map[string]any)
+		if !isMap {
+			return false
+		}
+		for key, value := range typed {
+			actualValue, found := actualMap[key]
+			if !found || !isJsonSubset(value, actualValue) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		actualList, isList := actual.([]any)
+		if !isList {
+			return false
+		}
+		for _, item := range typed {
+			found := false
+			for _, actualItem := range actualList {
+				if isJsonSubset(item, actualItem) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(expected, actual)
+	}
+}
+
+// normalizeJson converts a value decoded from either a JSON or a YAML mock file into the same
+// representation produced by decoding a JSON payload, so both can be compared.
+func normalizeJson(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Errorf("Failed to normalize body matcher %v: %v", value, err)
+		return value
+	}
+
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+func stringifyJson(value any) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(typed)
+	default:
+		data, err := json.Marshal(typed)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
@@ -3,7 +3,6 @@ package handler
 import (
 	"github.com/fsnotify/fsnotify"
 	log "github.com/sirupsen/logrus"
-	"github.com/softwareplace/mock-server/pkg/env"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,8 +11,14 @@ import (
 
 type OnFileChangDetected func(restartServer bool)
 
-func watchAndReload(onFileChangeDetected OnFileChangDetected) {
-	mockJsonFilesBasePath := env.GetAppEnv().MockPath
+// watchAndReload watches the mock files, reloading the mocks and calling the callback on change,
+// until the server is closed. The ready channel is closed once the files are watched.
+func (s *Server) watchAndReload(onFileChangeDetected OnFileChangDetected, ready chan<- struct{}) {
+	mockJsonFilesBasePath := s.env.MockPath
+	if mockJsonFilesBasePath == "" {
+		close(ready)
+		return
+	}
 
 	// Set up file watcher to reload mock responses and redirect rules on file changes
 	watcher, err := fsnotify.NewWatcher()
@@ -36,41 +41,31 @@ func watchAndReload(onFileChangeDetected OnFileChangDetected) {
 		log.Fatalf("Failed to watch directory: %v", err)
 	}
 
-	// Debouncing mechanism
-	var (
-		debounceDuration = 250 * time.Millisecond // Set the debounce duration to 1 second
-		lastEventTime    time.Time
-		timer            *time.Timer
-	)
+	s.watchBodyFiles(watcher)
+	close(ready)
+
+	// Debouncing mechanism. The timer and the changed file are only used by the goroutine of the watcher,
+	// which reloads the mocks once no event was received for the debounce duration.
+	debounceDuration := 250 * time.Millisecond
+	debounce := time.NewTimer(debounceDuration)
+	debounce.Stop()
 
 	go func() {
 		log.Infof("Starting file watcher...")
+		var changedFile string
 		for {
 			select {
 			case event, ok := <-watcher.Events:
 				if !ok {
+					debounce.Stop()
 					return
 				}
 
 				if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create || event.Op&fsnotify.Remove == fsnotify.Remove {
 
-					// Update the last event time
-					lastEventTime = time.Now()
-
-					// If a timer is already running, stop it
-					if timer != nil {
-						timer.Stop()
-					}
-
-					// Start a new timer
-					timer = time.AfterFunc(debounceDuration, func() {
-						// Check if the last event was more than debounceDuration ago
-						if time.Since(lastEventTime) >= debounceDuration {
-							log.Infof("File %s has changed. Reloading the server...", event.Name)
-							loadMockResponses()
-							onFileChangeDetected(true)
-						}
-					})
+					// Restart the debounce duration from the last event
+					changedFile = event.Name
+					debounce.Reset(debounceDuration)
 
 					// If a new file is created, add it to the watcher
 					if event.Op&fsnotify.Create == fsnotify.Create {
@@ -87,8 +82,14 @@ func watchAndReload(onFileChangeDetected OnFileChangDetected) {
 						}
 					}
 				}
+			case <-debounce.C:
+				log.Infof("File %s has changed. Reloading the mocks...", changedFile)
+				s.loadMockResponses()
+				s.watchBodyFiles(watcher)
+				s.reload(onFileChangeDetected)
 			case err, ok := <-watcher.Errors:
 				if !ok {
+					debounce.Stop()
 					return
 				}
 				log.Infof("File watcher error: %v", err)
@@ -97,7 +98,7 @@ func watchAndReload(onFileChangeDetected OnFileChangDetected) {
 	}()
 
 	defer func() {
-		<-make(chan struct{})
+		<-s.done
 		err := watcher.Close()
 		if err != nil {
 			log.Infof("Failed to close file watcher: %v", err)
@@ -106,6 +107,24 @@ func watchAndReload(onFileChangeDetected OnFileChangDetected) {
 	}()
 }
 
+// watchBodyFiles adds the body files referenced by the mock responses to the watcher, so the server
+// is also reloaded when they change, even if they are out of the mock directory.
+func (s *Server) watchBodyFiles(watcher *fsnotify.Watcher) {
+	for _, config := range s.Mocks() {
+		for index := range config.Response.Bodies {
+			body := &config.Response.Bodies[index]
+			if body.BodyFile == "" {
+				continue
+			}
+
+			bodyFile := s.resolveBodyFile(config, body)
+			if err := watcher.Add(bodyFile); err != nil {
+				log.Warnf("Failed to watch body file %s of %s: %v", bodyFile, config.MockFilePath, err)
+			}
+		}
+	}
+}
+
 func isValidFileType(info os.FileInfo) bool {
 	return strings.HasSuffix(info.Name(), ".json") ||
 		strings.HasSuffix(info.Name(), ".yaml") ||
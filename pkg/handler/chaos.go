@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"math/rand"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	defaultChaosStatusCodes = []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable}
+	defaultChaosLatency     = model.LatencyConfig{Distribution: model.DistributionUniform, Min: 100, Max: 1000}
+)
+
+// chaosStore keeps the chaos configuration, which starts as the one of the configuration file
+// and can be replaced at runtime, along with the random source seeded from it.
+type chaosStore struct {
+	mu     sync.RWMutex
+	config model.ChaosConfig
+	random *rand.Rand
+}
+
+func newChaosStore(config *model.ChaosConfig) *chaosStore {
+	store := &chaosStore{}
+	if config != nil {
+		store.config = *config
+	}
+	store.random = newRandom(store.config.Seed)
+	return store
+}
+
+// Config returns the current chaos configuration.
+func (s *chaosStore) Config() model.ChaosConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.config
+}
+
+// Set replaces the chaos configuration and reseeds the random source, so the chaos starts over.
+func (s *chaosStore) Set(config model.ChaosConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.config = config
+	s.random = newRandom(config.Seed)
+}
+
+// SetEnabled turns the chaos on or off, keeping the rest of its configuration.
+func (s *chaosStore) SetEnabled(enabled bool) model.ChaosConfig {
+	config := s.Config()
+	config.Enabled = enabled
+	s.Set(config)
+	return config
+}
+
+// Random returns the random source of the chaos.
+func (s *chaosStore) Random() *rand.Rand {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.random
+}
+
+// applyChaos randomly drops the connection, adds latency or replies with an error, according to the
+// chaos configuration. It returns true when the request was answered and must not be handled further.
+func (s *Server) applyChaos(ctx *apicontext.Request[*apicontext.DefaultContext]) bool {
+	config := s.chaos.Config()
+	if !config.Enabled || !inChaosScope(config, ctx.Request) {
+		return false
+	}
+
+	random := s.chaos.Random()
+	uri := ctx.Request.URL.RequestURI()
+
+	if config.DropRate > 0 && random.Float64() < config.DropRate {
+		log.Infof("Chaos dropped the connection of request %s::%s", ctx.Request.Method, uri)
+		closeConnection(ctx, true)
+		ctx.Done()
+		return true
+	}
+
+	if config.LatencyRate > 0 && random.Float64() < config.LatencyRate {
+		latency := defaultChaosLatency
+		if config.Latency != nil {
+			latency = *config.Latency
+		}
+
+		delay := sampleLatency(latency, random)
+		log.Infof("Chaos added %v of latency to request %s::%s", delay, ctx.Request.Method, uri)
+		time.Sleep(delay)
+	}
+
+	if config.ErrorRate > 0 && random.Float64() < config.ErrorRate {
+		statusCodes := config.ErrorStatusCodes
+		if len(statusCodes) == 0 {
+			statusCodes = defaultChaosStatusCodes
+		}
+
+		status := statusCodes[random.Intn(len(statusCodes))]
+		log.Infof("Chaos replied %d to request %s::%s", status, ctx.Request.Method, uri)
+		ctx.Error("Error injected by the chaos mode", status)
+		return true
+	}
+	return false
+}
+
+// inChaosScope checks if the request matches the methods and paths the chaos is scoped to.
+func inChaosScope(config model.ChaosConfig, request *http.Request) bool {
+	if len(config.Methods) > 0 && !slices.ContainsFunc(config.Methods, func(method string) bool {
+		return strings.EqualFold(method, request.Method)
+	}) {
+		return false
+	}
+
+	if len(config.Paths) == 0 {
+		return true
+	}
+
+	for _, pattern := range config.Paths {
+		if matched, err := path.Match(pattern, request.URL.Path); err == nil && matched {
+			return true
+		} else if err != nil {
+			log.Warnf("Invalid chaos path %q: %v", pattern, err)
+		}
+	}
+	return false
+}
+
+func (s *Server) registerChaosHandlers(appServer server.Api[*apicontext.DefaultContext]) {
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		ctx.Ok(s.chaos.Config())
+	}, AdminPath+"/chaos", http.MethodGet)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		var config model.ChaosConfig
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&config); err != nil {
+			ctx.BadRequest("The request body must be a valid chaos configuration")
+			return
+		}
+
+		s.chaos.Set(config)
+		log.Infof("Chaos configuration replaced, enabled: %t", config.Enabled)
+		ctx.Ok(s.chaos.Config())
+	}, AdminPath+"/chaos", http.MethodPut)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		log.Infof("Chaos enabled")
+		ctx.Ok(s.chaos.SetEnabled(true))
+	}, AdminPath+"/chaos/enable", http.MethodPost)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		log.Infof("Chaos disabled")
+		ctx.Ok(s.chaos.SetEnabled(false))
+	}, AdminPath+"/chaos/disable", http.MethodPost)
+}
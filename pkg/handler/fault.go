@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// resolveFault returns the fault of the body, falling back to the one of the response.
+func resolveFault(config model.MockConfigResponse, body *model.ResponseBody) string {
+	if body.Fault != "" {
+		return body.Fault
+	}
+	return config.Response.Fault
+}
+
+// writeFault simulates the given fault instead of writing a regular response.
+func (s *Server) writeFault(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	config model.MockConfigResponse,
+	body *interface{},
+	matchedBody *model.ResponseBody,
+	fault string,
+) {
+	defer ctx.Done()
+	log.Infof("Simulating the %s fault for request %s", fault, ctx.Request.URL.RequestURI())
+
+	status := resolveStatusCode(config, matchedBody)
+
+	switch fault {
+	case model.FaultEmptyResponse:
+		closeConnection(ctx, false)
+	case model.FaultConnectionReset:
+		closeConnection(ctx, true)
+	case model.FaultHangAfterHeaders:
+		writer := *ctx.Writer
+		writer.WriteHeader(status)
+		if flusher, ok := writer.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		<-ctx.Request.Context().Done()
+	case model.FaultTruncatedBody:
+		data, err := s.faultBody(config, body, matchedBody)
+		if err != nil {
+			ctx.Error("Failed to encode response body", http.StatusInternalServerError)
+			return
+		}
+		writeTruncatedBody(ctx, status, data)
+	case model.FaultMalformedJson:
+		data, err := s.faultBody(config, body, matchedBody)
+		if err != nil {
+			ctx.Error("Failed to encode response body", http.StatusInternalServerError)
+			return
+		}
+		malformed := append(data[:len(data)/2:len(data)/2], []byte(`,"}{]`)...)
+
+		writer := *ctx.Writer
+		writer.Header().Set("Content-Length", strconv.Itoa(len(malformed)))
+		writer.WriteHeader(status)
+		_, _ = writer.Write(malformed)
+	default:
+		log.Errorf("Unknown fault %q on %s", fault, config.MockFilePath)
+		ctx.Error(fmt.Sprintf("Unknown fault %s", fault), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) faultBody(config model.MockConfigResponse, body *interface{}, matchedBody *model.ResponseBody) ([]byte, error) {
+	if matchedBody.BodyFile != "" {
+		return os.ReadFile(s.resolveBodyFile(config, matchedBody))
+	}
+	return encodeBody(body, matchedBody.Encoding)
+}
+
+// hijack takes over the underlying connection of the request, so it can be closed or written to in raw.
+func hijack(ctx *apicontext.Request[*apicontext.DefaultContext]) (net.Conn, *bufio.ReadWriter, bool) {
+	hijacker, ok := (*ctx.Writer).(http.Hijacker)
+	if !ok {
+		log.Errorf("The response writer does not support hijacking the connection")
+		ctx.Error("The fault is not supported by this server", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	conn, buffer, err := hijacker.Hijack()
+	if err != nil {
+		log.Errorf("Failed to hijack the connection: %v", err)
+		ctx.Error("The fault is not supported by this server", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return conn, buffer, true
+}
+
+// closeConnection closes the connection without any reply. When reset is enabled, the
+// connection is closed with a TCP RST instead of a graceful FIN.
+func closeConnection(ctx *apicontext.Request[*apicontext.DefaultContext], reset bool) {
+	conn, _, ok := hijack(ctx)
+	if !ok {
+		return
+	}
+
+	if tcpConn, isTcp := conn.(*net.TCPConn); reset && isTcp {
+		_ = tcpConn.SetLinger(0)
+	}
+
+	if err := conn.Close(); err != nil {
+		log.Errorf("Failed to close the connection: %v", err)
+	}
+}
+
+// writeTruncatedBody writes the response headers, announcing a Content-Length larger than the body,
+// then only half of the body before closing the connection.
+func writeTruncatedBody(ctx *apicontext.Request[*apicontext.DefaultContext], status int, data []byte) {
+	writer := *ctx.Writer
+	header := writer.Header().Clone()
+
+	conn, buffer, ok := hijack(ctx)
+	if !ok {
+		return
+	}
+
+	defer func(conn net.Conn) {
+		if err := conn.Close(); err != nil {
+			log.Errorf("Failed to close the connection: %v", err)
+		}
+	}(conn)
+
+	header.Set("Content-Length", strconv.Itoa(len(data)+1))
+	header.Del("Transfer-Encoding")
+
+	_, _ = fmt.Fprintf(buffer, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	_ = header.Write(buffer)
+	_, _ = buffer.WriteString("\r\n")
+	_, _ = buffer.Write(data[:len(data)/2])
+
+	if err := buffer.Flush(); err != nil {
+		log.Errorf("Failed to write the truncated body: %v", err)
+	}
+}
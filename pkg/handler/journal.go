@@ -0,0 +1,342 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJournalSize     = 1000      // defaultJournalSize is the number of requests kept by the journal unless configured otherwise.
+	defaultJournalBodySize = 64 * 1024 // defaultJournalBodySize is the number of bytes of each request body kept unless configured otherwise.
+)
+
+// journalEntry is a request received by the mock server, along with how it was answered.
+type journalEntry struct {
+	Id            int64               `json:"id"`                      // Id identifies the request, increasing with each request received.
+	Time          time.Time           `json:"time"`                    // Time is when the request was received.
+	Method        string              `json:"method"`                  // Method is the HTTP method of the request.
+	Uri           string              `json:"uri"`                     // Uri is the requested URI, including the query string.
+	Path          string              `json:"path"`                    // Path is the path of the requested URI.
+	Headers       map[string][]string `json:"headers"`                 // Headers are the headers of the request.
+	Body          string              `json:"body"`                    // Body is the request body, up to the body size of the journal.
+	BodyTruncated bool                `json:"bodyTruncated,omitempty"` // BodyTruncated tells the Body only holds the beginning of the request body.
+	MockId        string              `json:"mockId,omitempty"`        // MockId is the id of the mock that handled the request, empty when none did.
+	MockFilePath  string              `json:"mockFilePath,omitempty"`  // MockFilePath is the file of the mock that handled the request.
+	BodyIndex     int                 `json:"bodyIndex"`               // BodyIndex is the position of the returned body in the mock, -1 when none of its bodies was returned.
+	DefaultBody   bool                `json:"defaultBody,omitempty"`   // DefaultBody tells the default body of the mock was returned, as none of its bodies matched.
+	Status        int                 `json:"status"`                  // Status is the response status code, 0 when the connection was taken over by a fault.
+	DurationMs    int64               `json:"durationMs"`              // DurationMs is the time taken to answer the request, in milliseconds.
+}
+
+// journalStore keeps the latest requests received by the mock server, up to its size.
+type journalStore struct {
+	mu       sync.RWMutex
+	size     int
+	bodySize int
+	entries  []journalEntry
+	lastId   int64
+}
+
+// Add records the entry, dropping the oldest one once the journal is full.
+func (s *journalStore) Add(entry journalEntry) {
+	size := s.size
+	if size <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastId++
+	entry.Id = s.lastId
+	s.entries = append(s.entries, entry)
+	if overflow := len(s.entries) - size; overflow > 0 {
+		s.entries = append(s.entries[:0:0], s.entries[overflow:]...)
+	}
+}
+
+// Find returns the recorded entries accepted by the filter, from the oldest to the newest.
+func (s *journalStore) Find(filter func(entry journalEntry) bool) []journalEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]journalEntry, 0)
+	for _, entry := range s.entries {
+		if filter(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Reset removes every recorded entry.
+func (s *journalStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = nil
+}
+
+// journalBodySize returns the number of bytes of each request body kept by the journal, a negative size keeps none.
+func journalBodySize(config *model.MockServerConfig) int {
+	if config.JournalBodySize != 0 {
+		return max(config.JournalBodySize, 0)
+	}
+	return defaultJournalBodySize
+}
+
+// journalSize returns the number of requests kept by the journal, a negative size disables it.
+func journalSize(config *model.MockServerConfig) int {
+	if config.JournalSize != 0 {
+		return config.JournalSize
+	}
+	return defaultJournalSize
+}
+
+// journalWriter records the status of the response written through it.
+type journalWriter struct {
+	http.ResponseWriter
+	entry *journalEntry
+}
+
+func (w *journalWriter) WriteHeader(status int) {
+	if w.entry.Status == 0 {
+		w.entry.Status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *journalWriter) Write(data []byte) (int, error) {
+	if w.entry.Status == 0 {
+		w.entry.Status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *journalWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *journalWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the response writer does not support hijacking the connection")
+	}
+	return hijacker.Hijack()
+}
+
+// journalRequest starts recording the request in the journal, returning the function that completes
+// the record once the request was answered. The mock is nil when no mock handles the request.
+func (s *Server) journalRequest(ctx *apicontext.Request[*apicontext.DefaultContext], mock *model.MockConfigResponse) func() {
+	if s.journal.size <= 0 {
+		return func() {}
+	}
+
+	// Only the kept part of the body is read, the rest is left to the handler of the request
+	body, truncated := peekRequestBody(ctx, s.journal.bodySize)
+
+	start := time.Now()
+	entry := &journalEntry{
+		Time:          start,
+		Method:        ctx.Request.Method,
+		Uri:           ctx.Request.URL.RequestURI(),
+		Path:          ctx.Request.URL.Path,
+		Headers:       ctx.Request.Header.Clone(),
+		Body:          string(body),
+		BodyTruncated: truncated,
+		BodyIndex:     -1,
+	}
+	if mock != nil {
+		entry.MockId = mock.Id
+		entry.MockFilePath = mock.MockFilePath
+	}
+
+	var writer http.ResponseWriter = &journalWriter{ResponseWriter: *ctx.Writer, entry: entry}
+	ctx.Writer = &writer
+
+	return func() {
+		entry.DurationMs = time.Since(start).Milliseconds()
+		s.journal.Add(*entry)
+	}
+}
+
+// journalMatchedBody records which body of the mock was returned for the request.
+func journalMatchedBody(ctx *apicontext.Request[*apicontext.DefaultContext], config model.MockConfigResponse, body *model.ResponseBody) {
+	writer, ok := (*ctx.Writer).(*journalWriter)
+	if !ok {
+		return
+	}
+
+	if body == config.Response.DefaultBody {
+		writer.entry.DefaultBody = true
+		return
+	}
+
+	for index := range config.Response.Bodies {
+		if &config.Response.Bodies[index] == body {
+			writer.entry.BodyIndex = index
+			return
+		}
+	}
+}
+
+// requestPattern describes the requests to look for in the journal. Every criterion is optional.
+type requestPattern struct {
+	Method  string              `json:"method"`  // Method of the request, case-insensitive.
+	Path    string              `json:"path"`    // Path of the request, or a glob such as /api/orders/*.
+	Queries map[string]any      `json:"queries"` // Queries matched the same way as the mock matching.
+	Headers map[string]any      `json:"headers"` // Headers matched the same way as the mock matching.
+	Body    *model.BodyMatching `json:"body"`    // Body matched the same way as the mock matching.
+}
+
+// verificationRequest asserts how many requests of the journal match the pattern. The request
+// must have been received at least once when no count is provided.
+type verificationRequest struct {
+	requestPattern
+	Count   *int `json:"count"`   // Count is the exact number of matching requests.
+	AtLeast *int `json:"atLeast"` // AtLeast is the minimum number of matching requests.
+	AtMost  *int `json:"atMost"`  // AtMost is the maximum number of matching requests.
+}
+
+type verificationResult struct {
+	Verified bool           `json:"verified"`
+	Count    int            `json:"count"`
+	Expected string         `json:"expected"`
+	Requests []journalEntry `json:"requests"`
+}
+
+// matchEntry checks if the recorded request matches the pattern.
+func (p requestPattern) matchEntry(entry journalEntry) bool {
+	if p.Method != "" && !strings.EqualFold(p.Method, entry.Method) {
+		return false
+	}
+
+	if p.Path != "" {
+		if matched, err := path.Match(p.Path, entry.Path); err != nil || !matched {
+			return false
+		}
+	}
+
+	if len(p.Queries) > 0 {
+		uri, err := url.ParseRequestURI(entry.Uri)
+		if err != nil {
+			return false
+		}
+
+		queries := uri.Query()
+		for key, expected := range p.Queries {
+			if !matchValue(expected, queries[key]) {
+				return false
+			}
+		}
+	}
+
+	headers := http.Header(entry.Headers)
+	for key, expected := range p.Headers {
+		if !matchValue(expected, headers.Values(key)) {
+			return false
+		}
+	}
+
+	if p.Body != nil {
+		if _, err := matchBody(*p.Body, []byte(entry.Body)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// verify checks the number of matching requests against the expected count.
+func (v verificationRequest) verify(count int) (bool, string) {
+	switch {
+	case v.Count != nil:
+		return count == *v.Count, fmt.Sprintf("exactly %d", *v.Count)
+	case v.AtLeast != nil && v.AtMost != nil:
+		return count >= *v.AtLeast && count <= *v.AtMost, fmt.Sprintf("between %d and %d", *v.AtLeast, *v.AtMost)
+	case v.AtLeast != nil:
+		return count >= *v.AtLeast, fmt.Sprintf("at least %d", *v.AtLeast)
+	case v.AtMost != nil:
+		return count <= *v.AtMost, fmt.Sprintf("at most %d", *v.AtMost)
+	default:
+		return count > 0, "at least 1"
+	}
+}
+
+// journalFilter returns the filter of the journal described by the query parameters of the request.
+func journalFilter(queries url.Values) (func(entry journalEntry) bool, error) {
+	pattern := requestPattern{Method: queries.Get("method"), Path: queries.Get("path")}
+	mockId := queries.Get("mock")
+	unmatched := isTrue(queries.Get("unmatched"))
+
+	status := 0
+	if value := queries.Get("status"); value != "" {
+		var err error
+		if status, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("invalid status %q", value)
+		}
+	}
+
+	return func(entry journalEntry) bool {
+		return pattern.matchEntry(entry) &&
+			(mockId == "" || entry.MockId == mockId) &&
+			(status == 0 || entry.Status == status) &&
+			(!unmatched || entry.BodyIndex < 0)
+	}, nil
+}
+
+func (s *Server) registerJournalHandlers(appServer server.Api[*apicontext.DefaultContext]) {
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		queries := ctx.Request.URL.Query()
+		filter, err := journalFilter(queries)
+		if err != nil {
+			ctx.BadRequest(err.Error())
+			return
+		}
+
+		entries := s.journal.Find(filter)
+		if limit, err := strconv.Atoi(queries.Get("limit")); err == nil && limit >= 0 && limit < len(entries) {
+			entries = entries[len(entries)-limit:]
+		}
+		ctx.Ok(entries)
+	}, AdminPath+"/requests", http.MethodGet)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		s.journal.Reset()
+		log.Infof("The request journal was cleared")
+		ctx.NoContent(nil)
+	}, AdminPath+"/requests", http.MethodDelete)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		var verification verificationRequest
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&verification); err != nil {
+			ctx.BadRequest(fmt.Sprintf("The request body must be a valid verification: %v", err))
+			return
+		}
+
+		entries := s.journal.Find(verification.matchEntry)
+		verified, expected := verification.verify(len(entries))
+		result := verificationResult{Verified: verified, Count: len(entries), Expected: expected, Requests: entries}
+
+		if !verified {
+			log.Warnf("Verification failed, expected %s matching requests, got %d", expected, len(entries))
+			ctx.Response(result, http.StatusExpectationFailed)
+			return
+		}
+		ctx.Ok(result)
+	}, AdminPath+"/requests/verify", http.MethodPost)
+}
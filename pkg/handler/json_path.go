@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is a single step of a JSONPath expression, selecting a property, an array index or every child.
+type jsonPathStep struct {
+	key      string
+	index    *int
+	wildcard bool
+}
+
+// evaluateJsonPath evaluates a JSONPath expression against a decoded JSON document and returns all the values found.
+//
+// The supported syntax is the root `$`, child access with `.name`, `['name']` or `["name"]`,
+// array index `[0]` (negative indexes count from the end) and wildcards `.*` or `[*]`, e.g.:
+//
+//	$.items[*].sku
+//	$.customer['first-name']
+//	$.items[-1].quantity
+func evaluateJsonPath(expression string, document any) ([]any, error) {
+	steps, err := parseJsonPath(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []any{document}
+	for _, step := range steps {
+		var next []any
+		for _, value := range values {
+			next = append(next, step.apply(value)...)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+func (step jsonPathStep) apply(value any) []any {
+	switch typed := value.(type) {
+	case map[string]any:
+		if step.wildcard {
+			values := make([]any, 0, len(typed))
+			for _, item := range typed {
+				values = append(values, item)
+			}
+			return values
+		}
+		if step.index != nil {
+			return nil
+		}
+		if item, found := typed[step.key]; found {
+			return []any{item}
+		}
+	case []any:
+		if step.wildcard {
+			return typed
+		}
+		if step.index == nil {
+			return nil
+		}
+		index := *step.index
+		if index < 0 {
+			index += len(typed)
+		}
+		if index >= 0 && index < len(typed) {
+			return []any{typed[index]}
+		}
+	}
+	return nil
+}
+
+func parseJsonPath(expression string) ([]jsonPathStep, error) {
+	expression = strings.TrimSpace(expression)
+	if !strings.HasPrefix(expression, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: it must start with $", expression)
+	}
+
+	var steps []jsonPathStep
+	remaining := expression[1:]
+
+	for remaining != "" {
+		switch remaining[0] {
+		case '.':
+			remaining = remaining[1:]
+			end := strings.IndexAny(remaining, ".[")
+			if end < 0 {
+				end = len(remaining)
+			}
+			name := remaining[:end]
+			if name == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty property name", expression)
+			}
+			steps = append(steps, jsonPathStep{key: name, wildcard: name == "*"})
+			remaining = remaining[end:]
+		case '[':
+			end := strings.Index(remaining, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: missing ]", expression)
+			}
+			selector := strings.TrimSpace(remaining[1:end])
+			remaining = remaining[end+1:]
+
+			switch {
+			case selector == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				steps = append(steps, jsonPathStep{key: selector[1 : len(selector)-1]})
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSONPath %q: unsupported selector [%s]", expression, selector)
+				}
+				steps = append(steps, jsonPathStep{index: &index})
+			}
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q", expression, remaining[0])
+		}
+	}
+
+	return steps, nil
+}
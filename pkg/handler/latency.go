@@ -0,0 +1,174 @@
+package handler
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// latencyRandom samples the latency of the mock responses.
+var latencyRandom = newRandom(0)
+
+// resolveLatency returns the latency of the body, then the one of the response, falling back to the global one.
+func (s *Server) resolveLatency(config model.MockConfigResponse, body *model.ResponseBody) *model.LatencyConfig {
+	if body.Latency != nil {
+		return body.Latency
+	}
+	if config.Response.Latency != nil {
+		return config.Response.Latency
+	}
+	return s.config.Latency
+}
+
+// resolveWait returns how long to wait before the response is sent. The fixed delay of the body comes
+// first, even when 0, then its latency, then the fixed delay of the response, its latency, and at last
+// the global latency.
+func (s *Server) resolveWait(config model.MockConfigResponse, body *model.ResponseBody) time.Duration {
+	if body.Delay != nil {
+		return time.Duration(max(*body.Delay, 0)) * time.Millisecond
+	}
+	if body.Latency != nil && body.Latency.Distribution != "" {
+		return sampleLatency(*body.Latency, latencyRandom)
+	}
+	if config.Response.Delay > 0 {
+		return time.Duration(config.Response.Delay) * time.Millisecond
+	}
+	if latency := s.resolveLatency(config, body); latency != nil {
+		return sampleLatency(*latency, latencyRandom)
+	}
+	return 0
+}
+
+// resolveBytesPerSecond returns the bandwidth the response body is throttled to, zero when unlimited.
+func (s *Server) resolveBytesPerSecond(config model.MockConfigResponse, body *model.ResponseBody) int {
+	if latency := s.resolveLatency(config, body); latency != nil {
+		return latency.BytesPerSecond
+	}
+	return 0
+}
+
+// sampleLatency returns a random delay following the latency distribution, clamped between its min and max.
+func sampleLatency(latency model.LatencyConfig, random *rand.Rand) time.Duration {
+	var milliseconds float64
+
+	switch latency.Distribution {
+	case "":
+		return 0
+	case model.DistributionUniform:
+		milliseconds = float64(latency.Min)
+		if latency.Max > latency.Min {
+			milliseconds += random.Float64() * float64(latency.Max-latency.Min)
+		}
+	case model.DistributionNormal:
+		milliseconds = latency.Mean + random.NormFloat64()*latency.StdDev
+	case model.DistributionLogNormal:
+		if latency.Mean <= 0 {
+			return 0
+		}
+		// Converts the mean and standard deviation of the samples to the parameters of the underlying normal distribution
+		sigma := math.Sqrt(math.Log(1 + (latency.StdDev*latency.StdDev)/(latency.Mean*latency.Mean)))
+		mu := math.Log(latency.Mean) - sigma*sigma/2
+		milliseconds = math.Exp(mu + random.NormFloat64()*sigma)
+	case model.DistributionPercentiles:
+		milliseconds = samplePercentiles(latency, random)
+	default:
+		log.Warnf("Unknown latency distribution %q", latency.Distribution)
+		return 0
+	}
+
+	milliseconds = math.Max(milliseconds, float64(latency.Min))
+	if latency.Max > 0 {
+		milliseconds = math.Min(milliseconds, float64(latency.Max))
+	}
+	return time.Duration(math.Max(milliseconds, 0) * float64(time.Millisecond))
+}
+
+type percentile struct {
+	rank  float64
+	value float64
+}
+
+// samplePercentiles returns a random latency shaped by the percentiles, such as {p50: 20, p99: 500},
+// interpolating linearly between them. The minimum latency is used as the p0, and the highest
+// percentile value as the p100 unless the maximum latency is provided.
+func samplePercentiles(latency model.LatencyConfig, random *rand.Rand) float64 {
+	points := []percentile{{rank: 0, value: float64(latency.Min)}}
+	for key, value := range latency.Percentiles {
+		rank, err := strconv.ParseFloat(strings.TrimPrefix(strings.ToLower(key), "p"), 64)
+		if err != nil || rank <= 0 || rank > 100 {
+			log.Warnf("Invalid latency percentile %q", key)
+			continue
+		}
+		points = append(points, percentile{rank: rank, value: float64(value)})
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].rank < points[j].rank
+	})
+
+	last := points[len(points)-1]
+	if last.rank < 100 {
+		maximum := last.value
+		if latency.Max > 0 {
+			maximum = float64(latency.Max)
+		}
+		points = append(points, percentile{rank: 100, value: maximum})
+	}
+
+	target := random.Float64() * 100
+	for index := 1; index < len(points); index++ {
+		lower, upper := points[index-1], points[index]
+		if target <= upper.rank {
+			ratio := (target - lower.rank) / (upper.rank - lower.rank)
+			return lower.value + ratio*(upper.value-lower.value)
+		}
+	}
+	return points[len(points)-1].value
+}
+
+// throttledWriter dribbles the data out to the underlying writer, limited to the given bytes per second.
+type throttledWriter struct {
+	writer         io.Writer
+	bytesPerSecond int
+}
+
+// throttleInterval is how often a chunk of the throttled data is written.
+const throttleInterval = 100 * time.Millisecond
+
+func newThrottledWriter(writer io.Writer, bytesPerSecond int) io.Writer {
+	if bytesPerSecond <= 0 {
+		return writer
+	}
+	return &throttledWriter{writer: writer, bytesPerSecond: bytesPerSecond}
+}
+
+func (w *throttledWriter) Write(data []byte) (int, error) {
+	chunkSize := max(w.bytesPerSecond*int(throttleInterval)/int(time.Second), 1)
+	interval := time.Duration(chunkSize) * time.Second / time.Duration(w.bytesPerSecond)
+
+	written := 0
+	for written < len(data) {
+		end := min(written+chunkSize, len(data))
+		n, err := w.writer.Write(data[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		if flusher, ok := w.writer.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		if written < len(data) {
+			time.Sleep(interval)
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Matcher operators supported by the matching values.
+//
+// A matching value can either be a plain value, that must be equal to the requested one, or a map
+// of operators that must all be satisfied, e.g.:
+//
+//	matching:
+//	  paths:
+//	    id:
+//	      gte: 100
+//	      lt: 200
+//	  headers:
+//	    authorization:
+//	      present: true
+const (
+	operatorRegex    = "regex"    // operatorRegex matches the value against a regular expression.
+	operatorPrefix   = "prefix"   // operatorPrefix matches values starting with the given string.
+	operatorSuffix   = "suffix"   // operatorSuffix matches values ending with the given string.
+	operatorContains = "contains" // operatorContains matches values containing the given string.
+	operatorAbsent   = "absent"   // operatorAbsent matches when the value is not part of the request.
+	operatorPresent  = "present"  // operatorPresent matches when the value is part of the request, whatever it is.
+	operatorNot      = "not"      // operatorNot matches values not equal to the given one.
+	operatorGt       = "gt"       // operatorGt matches numeric values greater than the given number.
+	operatorGte      = "gte"      // operatorGte matches numeric values greater than or equal to the given number.
+	operatorLt       = "lt"       // operatorLt matches numeric values less than the given number.
+	operatorLte      = "lte"      // operatorLte matches numeric values less than or equal to the given number.
+	operatorOneOf    = "oneOf"    // operatorOneOf matches values equal to one of the given list.
+	operatorAnyOf    = "anyOf"    // operatorAnyOf matches multi-valued parameters having at least one value of the given list.
+	operatorAllOf    = "allOf"    // operatorAllOf matches multi-valued parameters having every value of the given list, in any order.
+)
+
+// Scores given to the satisfied matchers, the more specific the matcher the higher its score.
+const (
+	scoreExact    = 4 // scoreExact is given to plain values, lists and exact documents.
+	scoreOperator = 2 // scoreOperator is given to each operator comparing the value, such as regex or ranges.
+	scorePresence = 1 // scorePresence is given to the present and absent operators.
+)
+
+var compiledRegexes sync.Map
+
+// matchValue checks if the requested values satisfy the expected matching value.
+//
+// A list requires the requested values to be exactly the given ones, in the same order. Otherwise,
+// only the first requested value is taken into account, except for the anyOf and allOf operators.
+func matchValue(expected any, values []string) bool {
+	if list, isList := expected.([]any); isList {
+		return matchList(list, values)
+	}
+
+	operators, isOperators := expected.(map[string]any)
+	if !isOperators {
+		return len(values) > 0 && values[0] == fmt.Sprintf("%v", expected)
+	}
+
+	for operator, operand := range operators {
+		if !matchOperator(operator, operand, values) {
+			return false
+		}
+	}
+	return true
+}
+
+// matcherScore returns the specificity of the expected matching value.
+func matcherScore(expected any) int {
+	operators, isOperators := expected.(map[string]any)
+	if !isOperators {
+		return scoreExact
+	}
+
+	score := 0
+	for operator := range operators {
+		if operator == operatorAbsent || operator == operatorPresent {
+			score += scorePresence
+		} else {
+			score += scoreOperator
+		}
+	}
+	return score
+}
+
+// isAbsentMatcher checks if the expected matching value requires the value to be absent from the request,
+// with either absent: true or present: false.
+func isAbsentMatcher(expected any) bool {
+	operators, isOperators := expected.(map[string]any)
+	if !isOperators {
+		return false
+	}
+	if absent, found := operators[operatorAbsent]; found && isTrue(absent) {
+		return true
+	}
+	present, found := operators[operatorPresent]
+	return found && !isTrue(present)
+}
+
+func matchOperator(operator string, operand any, values []string) bool {
+	present := len(values) > 0
+
+	switch operator {
+	case operatorAbsent:
+		return present != isTrue(operand)
+	case operatorPresent:
+		return present == isTrue(operand)
+	}
+
+	if !present {
+		return false
+	}
+
+	switch operator {
+	case operatorAnyOf:
+		for _, option := range toList(operand) {
+			if slices.Contains(values, option) {
+				return true
+			}
+		}
+		return false
+	case operatorAllOf:
+		for _, option := range toList(operand) {
+			if !slices.Contains(values, option) {
+				return false
+			}
+		}
+		return true
+	}
+
+	value := values[0]
+	expected := fmt.Sprintf("%v", operand)
+
+	switch operator {
+	case operatorRegex:
+		regex, err := compileRegex(expected)
+		if err != nil {
+			log.Errorf("Invalid regex matcher %q: %v", expected, err)
+			return false
+		}
+		return regex.MatchString(value)
+	case operatorPrefix:
+		return strings.HasPrefix(value, expected)
+	case operatorSuffix:
+		return strings.HasSuffix(value, expected)
+	case operatorContains:
+		return strings.Contains(value, expected)
+	case operatorNot:
+		return value != expected
+	case operatorGt, operatorGte, operatorLt, operatorLte:
+		return compareNumbers(operator, value, operand)
+	case operatorOneOf:
+		return slices.Contains(toList(operand), value)
+	default:
+		log.Warnf("Unknown matcher operator %q", operator)
+		return false
+	}
+}
+
+func matchList(expected []any, values []string) bool {
+	return slices.Equal(toList(expected), values)
+}
+
+// toList converts a matching operand into a list of strings. A single value becomes a list of one element.
+func toList(operand any) []string {
+	list, isList := operand.([]any)
+	if !isList {
+		return []string{fmt.Sprintf("%v", operand)}
+	}
+
+	values := make([]string, len(list))
+	for index, item := range list {
+		values[index] = fmt.Sprintf("%v", item)
+	}
+	return values
+}
+
+func compareNumbers(operator string, value string, operand any) bool {
+	actual, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+
+	expected, err := strconv.ParseFloat(fmt.Sprintf("%v", operand), 64)
+	if err != nil {
+		log.Errorf("Invalid numeric matcher %s: %v", operator, operand)
+		return false
+	}
+
+	switch operator {
+	case operatorGt:
+		return actual > expected
+	case operatorGte:
+		return actual >= expected
+	case operatorLt:
+		return actual < expected
+	default:
+		return actual <= expected
+	}
+}
+
+func compileRegex(expression string) (*regexp.Regexp, error) {
+	if regex, found := compiledRegexes.Load(expression); found {
+		return regex.(*regexp.Regexp), nil
+	}
+
+	regex, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	compiledRegexes.Store(expression, regex)
+	return regex, nil
+}
+
+func isTrue(value any) bool {
+	enabled, err := strconv.ParseBool(fmt.Sprintf("%v", value))
+	return err == nil && enabled
+}
+
+// expectedCount returns the number of values expected to be part of the request,
+// ignoring the ones that must be absent.
+func expectedCount(expected map[string]any) int {
+	count := 0
+	for _, value := range expected {
+		if !isAbsentMatcher(value) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,354 @@
+package handler
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/file"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// mockStore keeps the mock responses managed at runtime through the admin endpoints, which are merged
+// with the ones loaded from the mock files. A runtime mock with the id of a file mock overrides it,
+// and the hidden file mocks are the ones deleted without removing their file.
+type mockStore struct {
+	mu     sync.RWMutex
+	mocks  []model.MockConfigResponse
+	hidden map[string]bool
+}
+
+// Put adds the mock, or replaces the one with the same id.
+func (s *mockStore) Put(mock model.MockConfigResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.hidden, mock.Id)
+	index := slices.IndexFunc(s.mocks, func(existing model.MockConfigResponse) bool {
+		return existing.Id == mock.Id
+	})
+	if index >= 0 {
+		s.mocks[index] = mock
+	} else {
+		s.mocks = append(s.mocks, mock)
+	}
+}
+
+// Delete removes the runtime mock with the given id. When hide is enabled, the file mock with
+// the same id is no longer loaded either.
+func (s *mockStore) Delete(id string, hide bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mocks = slices.DeleteFunc(s.mocks, func(mock model.MockConfigResponse) bool {
+		return mock.Id == id
+	})
+	if hide {
+		s.hidden[id] = true
+	}
+}
+
+// Reset removes every runtime mock and shows the hidden file mocks again.
+func (s *mockStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mocks = nil
+	s.hidden = make(map[string]bool)
+}
+
+// Merge returns the file mocks, overridden or hidden by the runtime ones, followed by the new runtime mocks.
+func (s *mockStore) Merge(fileMocks []model.MockConfigResponse) []model.MockConfigResponse {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	merged := make([]model.MockConfigResponse, 0, len(fileMocks)+len(s.mocks))
+	overridden := make(map[string]bool)
+	for _, mock := range fileMocks {
+		if s.hidden[mock.Id] {
+			continue
+		}
+
+		index := slices.IndexFunc(s.mocks, func(runtime model.MockConfigResponse) bool {
+			return runtime.Id == mock.Id
+		})
+		if index >= 0 {
+			overridden[mock.Id] = true
+			mock = s.mocks[index]
+		}
+		merged = append(merged, mock)
+	}
+
+	for _, mock := range s.mocks {
+		if !overridden[mock.Id] {
+			merged = append(merged, mock)
+		}
+	}
+	return merged
+}
+
+// fileMockId returns the id of a mock loaded from a file, the one it declares or one derived from its path.
+func fileMockId(mock model.MockConfigResponse) string {
+	if mock.Id != "" {
+		return mock.Id
+	}
+	hash := sha1.Sum([]byte(filepath.ToSlash(mock.MockFilePath)))
+	return hex.EncodeToString(hash[:6])
+}
+
+// newMockId returns a random id for a mock managed at runtime.
+func newMockId() string {
+	return uuid.NewString()
+}
+
+// findMock returns the loaded mock with the given id.
+func (s *Server) findMock(id string) (model.MockConfigResponse, bool) {
+	return s.registry.Snapshot().mock(id)
+}
+
+// validMockId restricts the ids of the runtime mocks to the characters that are safe in a file name.
+var validMockId = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9._-]*$`)
+
+// validateMock checks that the mock can be registered, the same way Register does.
+func validateMock(mock model.MockConfigResponse) error {
+	if mock.Request.Method == "" || mock.Request.Path == "" {
+		return errors.New("the request method and path are required")
+	}
+	if mock.Id != "" && (!validMockId.MatchString(mock.Id) || strings.Contains(mock.Id, "..")) {
+		return fmt.Errorf("the id %q may only contain letters, digits, dots, dashes and underscores", mock.Id)
+	}
+	if mock.Redirect.Url == "" && mock.Response.Bodies == nil {
+		return errors.New("a response body or a redirect URL is required")
+	}
+
+	switch mock.Response.OnNoMatch {
+	case "", model.OnNoMatchNotFound, model.OnNoMatchGlobalRedirect:
+	case model.OnNoMatchDefaultBody:
+		if mock.Response.DefaultBody == nil {
+			return errors.New("a default body is required by the default-body policy")
+		}
+	case model.OnNoMatchRedirect:
+		if mock.Redirect.Url == "" {
+			return errors.New("a redirect URL is required by the redirect policy")
+		}
+	default:
+		return fmt.Errorf("unknown on-no-match policy %q", mock.Response.OnNoMatch)
+	}
+	return nil
+}
+
+// persistMock writes the mock to its file, or to a new file in the mock directory, in the format of its extension.
+// A new file never overwrites an existing one, failing with an error matching os.ErrExist instead.
+func (s *Server) persistMock(mock *model.MockConfigResponse) error {
+	newFile := mock.MockFilePath == ""
+	if newFile {
+		filePath, err := s.newMockFilePath(mock.Id)
+		if err != nil {
+			return err
+		}
+		mock.MockFilePath = filePath
+	}
+
+	// The file path is not part of the mock file content
+	content := *mock
+	content.MockFilePath = ""
+
+	data, err := encodeMockFile(mock.MockFilePath, content)
+	if err != nil {
+		return fmt.Errorf("failed to encode the mock: %w", err)
+	}
+
+	if newFile {
+		return file.CreateFile(data, mock.MockFilePath)
+	}
+	return file.SaveToFile(data, mock.MockFilePath)
+}
+
+// newMockFilePath returns the file of a new mock in the mock directory, named after its sanitized id.
+func (s *Server) newMockFilePath(id string) (string, error) {
+	name := strings.Trim(unsafeFileNameChars.ReplaceAllString(id, "_"), "._")
+	if name == "" {
+		return "", fmt.Errorf("the id %q is not a valid file name", id)
+	}
+
+	filePath := filepath.Join(s.env.MockPath, name+".json")
+	if relative, err := filepath.Rel(s.env.MockPath, filePath); err != nil || !filepath.IsLocal(relative) {
+		return "", fmt.Errorf("the mock file %s is outside of the mock directory", filePath)
+	}
+	return filePath, nil
+}
+
+// reloadMocks reloads the mock responses, then calls the callback of LoadResponses to notify the change.
+// The callback runs in the background when requested by an admin endpoint, since its request is still being served.
+func (s *Server) reloadMocks(background bool) {
+	s.loadMockResponses()
+
+	s.reloadMu.Lock()
+	callback := s.onReload
+	s.reloadMu.Unlock()
+
+	if background {
+		go s.reload(callback)
+	} else {
+		s.reload(callback)
+	}
+}
+
+// reload calls the callback of LoadResponses once the mocks changed, one call at a time.
+func (s *Server) reload(callback OnFileChangDetected) {
+	if callback == nil {
+		return
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	callback(true)
+}
+
+func isPersistRequested(ctx *apicontext.Request[*apicontext.DefaultContext]) bool {
+	return isTrue(ctx.Request.URL.Query().Get("persist"))
+}
+
+func decodeMock(ctx *apicontext.Request[*apicontext.DefaultContext]) (model.MockConfigResponse, bool) {
+	var mock model.MockConfigResponse
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&mock); err != nil {
+		ctx.BadRequest(fmt.Sprintf("The request body must be a valid mock: %v", err))
+		return mock, false
+	}
+	if err := validateMock(mock); err != nil {
+		ctx.BadRequest(fmt.Sprintf("Invalid mock: %v", err))
+		return mock, false
+	}
+	return mock, true
+}
+
+func (s *Server) registerMockHandlers(appServer server.Api[*apicontext.DefaultContext]) {
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		mocks := s.Mocks()
+		if mocks == nil {
+			mocks = []model.MockConfigResponse{}
+		}
+		ctx.Ok(mocks)
+	}, AdminPath+"/mocks", http.MethodGet)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		mock, found := s.findMock(ctx.PathValues["id"])
+		if !found {
+			ctx.NotFount("Mock not found")
+			return
+		}
+		ctx.Ok(mock)
+	}, AdminPath+"/mocks/{id}", http.MethodGet)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		mock, ok := decodeMock(ctx)
+		if !ok {
+			return
+		}
+
+		if mock.Id == "" {
+			mock.Id = newMockId()
+		} else if _, found := s.findMock(mock.Id); found {
+			ctx.Error(fmt.Sprintf("A mock with the id %s already exists", mock.Id), http.StatusConflict)
+			return
+		}
+
+		mock.MockFilePath = ""
+		if !s.saveMock(ctx, mock) {
+			return
+		}
+
+		log.Infof("Mock %s created for %s::%s", mock.Id, mock.Request.Method, mock.Request.Path)
+		created, _ := s.findMock(mock.Id)
+		ctx.Response(created, http.StatusCreated)
+	}, AdminPath+"/mocks", http.MethodPost)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		id := ctx.PathValues["id"]
+		existing, found := s.findMock(id)
+		if !found {
+			ctx.NotFount("Mock not found")
+			return
+		}
+
+		mock, ok := decodeMock(ctx)
+		if !ok {
+			return
+		}
+
+		mock.Id = id
+		mock.MockFilePath = existing.MockFilePath
+		if !s.saveMock(ctx, mock) {
+			return
+		}
+
+		log.Infof("Mock %s updated for %s::%s", mock.Id, mock.Request.Method, mock.Request.Path)
+		updated, _ := s.findMock(mock.Id)
+		ctx.Ok(updated)
+	}, AdminPath+"/mocks/{id}", http.MethodPut)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		id := ctx.PathValues["id"]
+		existing, found := s.findMock(id)
+		if !found {
+			ctx.NotFount("Mock not found")
+			return
+		}
+
+		persist := isPersistRequested(ctx)
+		if persist && existing.MockFilePath != "" {
+			if err := os.Remove(existing.MockFilePath); err != nil {
+				log.Errorf("Failed to remove mock file %s: %v", existing.MockFilePath, err)
+				ctx.Error("Failed to remove the mock file", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		s.runtimeMocks.Delete(id, !persist && existing.MockFilePath != "")
+		s.reloadMocks(true)
+
+		log.Infof("Mock %s deleted", id)
+		ctx.NoContent(nil)
+	}, AdminPath+"/mocks/{id}", http.MethodDelete)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		s.runtimeMocks.Reset()
+		s.reloadMocks(true)
+
+		log.Infof("All runtime mocks were removed")
+		ctx.NoContent(nil)
+	}, AdminPath+"/mocks/reset", http.MethodPost)
+}
+
+// saveMock keeps the mock in memory, or writes it to its file when persisting is requested, then reloads the mocks.
+func (s *Server) saveMock(ctx *apicontext.Request[*apicontext.DefaultContext], mock model.MockConfigResponse) bool {
+	if isPersistRequested(ctx) {
+		if err := s.persistMock(&mock); err != nil {
+			if errors.Is(err, os.ErrExist) {
+				ctx.Error(fmt.Sprintf("The mock file of %s already exists", mock.Id), http.StatusConflict)
+				return false
+			}
+			log.Errorf("Failed to persist mock %s: %v", mock.Id, err)
+			ctx.Error("Failed to persist the mock", http.StatusInternalServerError)
+			return false
+		}
+		s.runtimeMocks.Delete(mock.Id, false)
+	} else {
+		s.runtimeMocks.Put(mock)
+	}
+
+	s.reloadMocks(true)
+	return true
+}
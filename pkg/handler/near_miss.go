@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxNearMisses is the number of closest mocks reported when no mock handles the request.
+const maxNearMisses = 3
+
+// nearMiss is a mock that almost handled the request, along with why it did not.
+type nearMiss struct {
+	MockId       string     `json:"mockId"`
+	MockFilePath string     `json:"mockFilePath"`
+	Method       string     `json:"method"`
+	Path         string     `json:"path"`
+	Reason       string     `json:"reason,omitempty"` // Reason describes why the route of the mock does not match the request.
+	Bodies       []bodyMiss `json:"bodies,omitempty"` // Bodies describe why each body of the mock does not match the request.
+	score        int
+}
+
+// bodyMiss describes why a body of the mock does not match the request.
+type bodyMiss struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// notFoundReport is the response of an unmatched request when the debug mode is enabled.
+type notFoundReport struct {
+	Message    string     `json:"message"`
+	StatusCode int        `json:"statusCode"`
+	Request    string     `json:"request"`
+	NearMisses []nearMiss `json:"nearMisses"`
+}
+
+// writeNoBodyMatch answers the request handled by a mock whose bodies do not match it, listing why
+// each body does not match in the log, and in the response when the debug mode is enabled.
+func (s *Server) writeNoBodyMatch(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	config model.MockConfigResponse,
+	matches []bodyMatch,
+) {
+	miss := nearMiss{
+		MockId:       config.Id,
+		MockFilePath: config.MockFilePath,
+		Method:       config.Request.Method,
+		Path:         config.Request.Path,
+	}
+	for _, match := range matches {
+		if match.err != nil {
+			miss.Bodies = append(miss.Bodies, bodyMiss{Index: match.index, Reason: match.err.Error()})
+		}
+	}
+
+	s.writeNotMatched(ctx, []nearMiss{miss})
+}
+
+// writeNoRouteMatch answers the request no mock is registered for, listing the closest mocks in
+// the log, and in the response when the debug mode is enabled.
+func (s *Server) writeNoRouteMatch(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+	s.writeNotMatched(ctx, s.closestMocks(ctx.Request.Method, ctx.Request.URL.Path))
+}
+
+// writeNotMatched answers the unmatched request, with the near-miss diagnostics when the debug mode is enabled.
+func (s *Server) writeNotMatched(ctx *apicontext.Request[*apicontext.DefaultContext], nearMisses []nearMiss) {
+	request := fmt.Sprintf("%s %s", ctx.Request.Method, ctx.Request.URL.RequestURI())
+	log.Warnf("No mock matches request %s%s", request, describeNearMisses(nearMisses))
+
+	if !s.env.Debug {
+		writer := *ctx.Writer
+		writer.WriteHeader(http.StatusNotFound)
+		_, _ = writer.Write([]byte("Resource not found"))
+		ctx.Done()
+		return
+	}
+
+	if nearMisses == nil {
+		nearMisses = []nearMiss{}
+	}
+	ctx.Response(notFoundReport{
+		Message:    "Resource not found",
+		StatusCode: http.StatusNotFound,
+		Request:    request,
+		NearMisses: nearMisses,
+	}, http.StatusNotFound)
+}
+
+func describeNearMisses(nearMisses []nearMiss) string {
+	if len(nearMisses) == 0 {
+		return ", no mock is close to it"
+	}
+
+	var builder strings.Builder
+	for _, miss := range nearMisses {
+		_, _ = fmt.Fprintf(&builder, "\n  - %s::%s (%s)", miss.Method, miss.Path, miss.MockFilePath)
+		if miss.Reason != "" {
+			_, _ = fmt.Fprintf(&builder, ": %s", miss.Reason)
+		}
+		for _, body := range miss.Bodies {
+			_, _ = fmt.Fprintf(&builder, "\n      body #%d: %s", body.Index, body.Reason)
+		}
+	}
+	return builder.String()
+}
+
+// closestMocks returns the mocks whose route is the closest to the request, the ones sharing the most
+// path segments first, along with why their route does not match it.
+func (s *Server) closestMocks(method string, requestPath string) []nearMiss {
+	requestSegments := pathSegments(strings.TrimPrefix(requestPath, strings.TrimSuffix(s.env.ContextPath, "/")))
+
+	var nearMisses []nearMiss
+	for _, config := range s.Mocks() {
+		score, reason := routeDistance(config, method, requestSegments)
+		if score <= 0 {
+			continue
+		}
+
+		nearMisses = append(nearMisses, nearMiss{
+			MockId:       config.Id,
+			MockFilePath: config.MockFilePath,
+			Method:       config.Request.Method,
+			Path:         config.Request.Path,
+			Reason:       reason,
+			score:        score,
+		})
+	}
+
+	sort.SliceStable(nearMisses, func(i, j int) bool {
+		return nearMisses[i].score > nearMisses[j].score
+	})
+	if len(nearMisses) > maxNearMisses {
+		nearMisses = nearMisses[:maxNearMisses]
+	}
+	return nearMisses
+}
+
+// routeDistance scores how close the route of the mock is to the request, by the number of path segments
+// they share, less the ones one has and not the other. The same method is worth one more segment.
+func routeDistance(config model.MockConfigResponse, method string, requestSegments []string) (int, string) {
+	mockSegments := pathSegments(config.Request.Path)
+
+	score := 0
+	var reasons []string
+	for index := 0; index < min(len(mockSegments), len(requestSegments)); index++ {
+		expected, actual := mockSegments[index], requestSegments[index]
+		if expected == actual || strings.HasPrefix(expected, "{") && strings.HasSuffix(expected, "}") {
+			score++
+		} else if len(reasons) == 0 {
+			reasons = append(reasons, fmt.Sprintf("path segment #%d is %q, expected %q", index+1, actual, expected))
+		}
+	}
+
+	if len(mockSegments) != len(requestSegments) {
+		score -= max(len(mockSegments), len(requestSegments)) - min(len(mockSegments), len(requestSegments))
+		reasons = append(reasons, fmt.Sprintf("path has %d segments, expected %d", len(requestSegments), len(mockSegments)))
+	}
+
+	if strings.EqualFold(config.Request.Method, method) {
+		score++
+	} else {
+		reasons = append(reasons, fmt.Sprintf("method is %s, expected %s", method, config.Request.Method))
+	}
+	return score, strings.Join(reasons, "; ")
+}
+
+func pathSegments(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
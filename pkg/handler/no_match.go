@@ -0,0 +1,55 @@
+package handler
+
+import (
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/mock-server/pkg/config"
+	"github.com/softwareplace/mock-server/pkg/model"
+)
+
+// writeOnNoMatch answers the request none of the bodies of the mock matches, as set by its OnNoMatch policy.
+// A policy that cannot be applied, such as a redirect without URL or one that did not answer the request,
+// falls back to OnNoMatchNotFound.
+func (s *Server) writeOnNoMatch(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	mock model.MockConfigResponse,
+	matches []bodyMatch,
+) {
+	switch policy := s.resolveOnNoMatch(mock); policy {
+	case model.OnNoMatchNotFound:
+	case model.OnNoMatchDefaultBody:
+		if mock.Response.DefaultBody != nil {
+			journalMatchedBody(ctx, mock, mock.Response.DefaultBody)
+			s.writeResponseBody(ctx, mock, mock.Response.DefaultBody)
+			return
+		}
+		log.Warnf("No default body found in %s for the %s policy", mock.MockFilePath, policy)
+	case model.OnNoMatchRedirect:
+		if mock.Redirect.Url != "" && s.requestRedirectHandler(ctx, mock.Redirect) {
+			return
+		}
+		log.Warnf("No redirect URL found in %s for the %s policy", mock.MockFilePath, policy)
+	case model.OnNoMatchGlobalRedirect:
+		if config.HasAValidRedirectConfig(s.config) && s.requestRedirectHandler(ctx, *s.config.RedirectConfig) {
+			return
+		}
+		log.Warnf("No redirect URL found in the configuration file for the %s policy of %s", policy, mock.MockFilePath)
+	default:
+		log.Warnf("Unknown on-no-match policy %q in %s", policy, mock.MockFilePath)
+	}
+
+	s.writeNoBodyMatch(ctx, mock, matches)
+}
+
+// resolveOnNoMatch returns the OnNoMatch policy of the mock. Unless provided, the requests are redirected to the
+// global redirect while it is recording, so the requests of the recorded routes that were not recorded yet are
+// recorded as well, unless SkipUnmatched opts out of it. OnNoMatchNotFound applies otherwise.
+func (s *Server) resolveOnNoMatch(mock model.MockConfigResponse) string {
+	if mock.Response.OnNoMatch != "" {
+		return mock.Response.OnNoMatch
+	}
+	if redirect := s.config.RedirectConfig; redirect != nil && isRecording(*redirect) && !redirect.Record.SkipUnmatched {
+		return model.OnNoMatchGlobalRedirect
+	}
+	return model.OnNoMatchNotFound
+}
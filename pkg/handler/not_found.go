@@ -3,16 +3,20 @@ package handler
 import (
 	apicontext "github.com/softwareplace/goserve/context"
 	"github.com/softwareplace/mock-server/pkg/config"
-	"github.com/softwareplace/mock-server/pkg/model"
 	"net/http"
 )
 
-func NotFound(w http.ResponseWriter, r *http.Request) {
+// NotFound answers the requests no mock is registered for, redirecting them when a global redirect is configured.
+func (s *Server) NotFound(w http.ResponseWriter, r *http.Request) {
 	ctx := apicontext.Of[*apicontext.DefaultContext](w, r, "MOCK/NOT/FOUND/HANDLER")
-	if config.HasAValidRedirectConfig() {
-		redirectConfig := model.Config.RedirectConfig
-		requestRedirectHandler(ctx, *redirectConfig)
+	defer s.journalRequest(ctx, nil)()
+	if s.applyChaos(ctx) {
+		return
+	}
+	if config.HasAValidRedirectConfig(s.config) {
+		redirectConfig := s.config.RedirectConfig
+		s.requestRedirectHandler(ctx, *redirectConfig)
 	} else {
-		ctx.NotFount("Resource not found")
+		s.writeNoRouteMatch(ctx)
 	}
 }
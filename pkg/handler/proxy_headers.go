@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// hopByHopHeaders describe the connection between the client and the mock server, and are never proxied.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// forwardRequestHeaders copies the headers of the client request selected by the redirect to the upstream request,
+// then sets the headers of the redirect, which take precedence over the forwarded ones.
+func forwardRequestHeaders(source *http.Request, target *http.Request, redirect model.RedirectConfig) {
+	forward := model.ForwardConfig{}
+	if redirect.ForwardHeaders != nil {
+		forward = *redirect.ForwardHeaders
+	}
+
+	for key, values := range source.Header {
+		if isForwardedHeader(key, forward) {
+			target.Header[key] = slices.Clone(values)
+		}
+	}
+	removeHopByHopHeaders(target.Header, source.Header)
+	// The transport negotiates the compression and decompresses the response, which is logged and recorded as is
+	target.Header.Del("Accept-Encoding")
+
+	if forward.XForwarded {
+		setForwardedHeaders(source, target.Header)
+	}
+
+	for key, value := range redirect.Headers {
+		target.Header.Set(key, fmt.Sprintf("%v", value))
+	}
+}
+
+func isForwardedHeader(key string, forward model.ForwardConfig) bool {
+	if containsHeader(forward.Deny, key) {
+		return false
+	}
+	if len(forward.Allow) > 0 {
+		return containsHeader(forward.Allow, key)
+	}
+	return forward.Mode != model.ForwardModeNone
+}
+
+func containsHeader(headers []string, key string) bool {
+	return slices.ContainsFunc(headers, func(header string) bool {
+		return strings.EqualFold(header, key)
+	})
+}
+
+// removeHopByHopHeaders removes the hop-by-hop headers, along with the ones listed by the Connection header of the original message.
+func removeHopByHopHeaders(header http.Header, original http.Header) {
+	for _, value := range original.Values("Connection") {
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				header.Del(key)
+			}
+		}
+	}
+	for _, key := range hopByHopHeaders {
+		header.Del(key)
+	}
+}
+
+// setForwardedHeaders describes the client request to the upstream, appending the client address to the proxies
+// listed in X-Forwarded-For.
+func setForwardedHeaders(source *http.Request, header http.Header) {
+	if clientIP, _, err := net.SplitHostPort(source.RemoteAddr); err == nil {
+		if prior := source.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			clientIP = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		header.Set("X-Forwarded-For", clientIP)
+	}
+
+	header.Set("X-Forwarded-Host", source.Host)
+
+	proto := "http"
+	if source.TLS != nil {
+		proto = "https"
+	}
+	header.Set("X-Forwarded-Proto", proto)
+}
+
+// rewriteResponseHeaders applies the rules of the redirect to the headers of the upstream response.
+func rewriteResponseHeaders(header http.Header, rules []model.HeaderRule) {
+	for _, rule := range rules {
+		switch {
+		case rule.Remove:
+			header.Del(rule.Name)
+		case rule.Value != "":
+			header.Set(rule.Name, rule.Value)
+		case rule.Old != "":
+			values := header.Values(rule.Name)
+			for index, value := range values {
+				values[index] = strings.ReplaceAll(value, rule.Old, rule.New)
+			}
+		}
+	}
+}
+
+// copyResponseHeaders copies the end-to-end headers of the upstream response to the client response.
+func copyResponseHeaders(target http.Header, source http.Header) {
+	for key, values := range source {
+		target[key] = slices.Clone(values)
+	}
+	removeHopByHopHeaders(target, source)
+}
@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/file"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// unrecordedHeaders are the response headers that describe the connection or the original exchange,
+// rather than the response, and are not written to the recorded mocks.
+var unrecordedHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Date":              true,
+	"Keep-Alive":        true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// isRecording checks if the redirected requests are recorded.
+func isRecording(redirect model.RedirectConfig) bool {
+	return redirect.Record != nil && redirect.Record.Enabled
+}
+
+// recordResponse writes the redirected request and its response as a mock file, adding a body to the
+// file of the same route when it already exists. A body with the same matching is replaced instead.
+func (s *Server) recordResponse(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	record model.RecordConfig,
+	requestBody []byte,
+	response *http.Response,
+	responseBody []byte,
+) {
+	path := strings.TrimPrefix(ctx.Request.URL.Path, strings.TrimSuffix(s.env.ContextPath, "/"))
+	path = "/" + strings.TrimPrefix(path, "/")
+
+	filePath := s.recordFilePath(record, ctx.Request.Method, path)
+	body := recordedBody(ctx.Request, requestBody, response, responseBody)
+
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+
+	mock := model.MockConfigResponse{
+		Request: model.RequestConfig{Method: ctx.Request.Method, Path: path},
+	}
+	if data, err := os.ReadFile(filePath); err == nil {
+		if err := decodeMockFile(filePath, data, &mock); err != nil {
+			log.Errorf("Failed to merge the recording into %s: %v", filePath, err)
+			return
+		}
+	}
+
+	mock.Response.Bodies = mergeRecordedBody(mock.Response.Bodies, body)
+
+	data, err := encodeMockFile(filePath, mock)
+	if err != nil {
+		log.Errorf("Failed to encode the recording of %s::%s: %v", ctx.Request.Method, path, err)
+		return
+	}
+	if err := file.SaveToFile(data, filePath); err != nil {
+		log.Errorf("Failed to save the recording to %s: %v", filePath, err)
+		return
+	}
+	log.Infof("Recorded %s::%s to %s", ctx.Request.Method, ctx.Request.URL.RequestURI(), filePath)
+}
+
+// recordFilePath returns the mock file of the route, such as get-api-users-1.yaml.
+func (s *Server) recordFilePath(record model.RecordConfig, method string, path string) string {
+	dir := env.UserHomePathFix(record.Dir)
+	if dir == "" {
+		dir = s.env.MockPath
+	}
+
+	extension := ".yaml"
+	if record.Format == model.RecordFormatJson {
+		extension = ".json"
+	}
+
+	name := strings.Trim(unsafeFileNameChars.ReplaceAllString(strings.Join(pathSegments(path), "-"), "_"), "_")
+	if name == "" {
+		name = "root"
+	}
+	return filepath.Join(dir, strings.ToLower(method)+"-"+name+extension)
+}
+
+// recordedBody returns the body replaying the response, matching the query parameters and the JSON payload of the request.
+func recordedBody(request *http.Request, requestBody []byte, response *http.Response, responseBody []byte) model.ResponseBody {
+	body := model.ResponseBody{
+		StatusCode:  response.StatusCode,
+		ContentType: response.Header.Get("Content-Type"),
+	}
+
+	matching := model.Matching{Queries: make(map[string]any)}
+	for key, values := range request.URL.Query() {
+		if len(values) == 1 {
+			matching.Queries[key] = values[0]
+		} else {
+			list := make([]any, len(values))
+			for index, value := range values {
+				list[index] = value
+			}
+			matching.Queries[key] = list
+		}
+	}
+	if payload, isJson := parseRequestBody(request.Header.Get("Content-Type"), requestBody).(map[string]any); isJson {
+		matching.Body = &model.BodyMatching{EqualTo: payload}
+	}
+	body.Matching = &matching
+
+	headers := make(map[string]any)
+	for key, values := range response.Header {
+		if !unrecordedHeaders[http.CanonicalHeaderKey(key)] {
+			headers[key] = strings.Join(values, ", ")
+		}
+	}
+	if len(headers) > 0 {
+		body.Headers = &headers
+	}
+
+	var content any
+	switch {
+	case len(responseBody) == 0:
+		return body
+	case strings.Contains(body.ContentType, "json") && json.Unmarshal(responseBody, &content) == nil:
+	case utf8.Valid(responseBody):
+		content = string(responseBody)
+	default:
+		content = base64.StdEncoding.EncodeToString(responseBody)
+		body.Encoding = model.EncodingBase64
+	}
+	body.Body = &content
+	return body
+}
+
+// mergeRecordedBody replaces the body with the same matching, or appends the recorded one.
+func mergeRecordedBody(bodies []model.ResponseBody, recorded model.ResponseBody) []model.ResponseBody {
+	recordedMatching := matchingKey(recorded.Matching)
+	for index, body := range bodies {
+		if matchingKey(body.Matching) == recordedMatching {
+			bodies[index] = recorded
+			return bodies
+		}
+	}
+	return append(bodies, recorded)
+}
+
+// matchingKey returns the compacted JSON of the matching, so a matching read back from a mock file
+// is equal to the recorded one, regardless of the settings left out of the file.
+func matchingKey(matching *model.Matching) string {
+	var document any
+	if data, err := json.Marshal(matching); err == nil {
+		_ = json.Unmarshal(data, &document)
+	}
+	key, _ := json.Marshal(compactDocument(document))
+	return string(key)
+}
+
+func decodeMockFile(filePath string, data []byte, mock *model.MockConfigResponse) error {
+	if strings.HasSuffix(filePath, ".json") {
+		return json.Unmarshal(data, mock)
+	}
+	return yaml.Unmarshal(data, mock)
+}
+
+// encodeMockFile encodes the mock in the format of the file extension, leaving out the settings it does not use.
+func encodeMockFile(filePath string, mock model.MockConfigResponse) ([]byte, error) {
+	var document map[string]any
+	if strings.HasSuffix(filePath, ".json") {
+		data, err := json.Marshal(mock)
+		if err == nil {
+			err = json.Unmarshal(data, &document)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(compactDocument(document), "", "  ")
+	}
+
+	data, err := yaml.Marshal(mock)
+	if err == nil {
+		err = yaml.Unmarshal(data, &document)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(compactDocument(document))
+}
+
+// verbatimKeys hold the data of the mocks, such as the bodies and the matching values, which are kept as they are.
+// Only the ones whose empty value means the same as no value at all are left out when empty.
+var verbatimKeys = map[string]bool{
+	"body": false, "equal-to": false, "equalTo": false, "partial": false, "queries": false,
+	"json-path": true, "jsonPath": true, "form": true, "paths": true, "headers": true, "percentiles": true,
+}
+
+// compactDocument removes the empty settings of the encoded mock, such as the null pointers and zero values.
+func compactDocument(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		compacted := make(map[string]any)
+		for key, item := range typed {
+			if item == nil {
+				continue
+			}
+			omitEmpty, verbatim := verbatimKeys[key]
+			if !verbatim {
+				item = compactDocument(item)
+				omitEmpty = true
+			}
+			if omitEmpty && isEmptyValue(item) {
+				continue
+			}
+			compacted[key] = item
+		}
+		return compacted
+	case []any:
+		compacted := make([]any, len(typed))
+		for index, item := range typed {
+			compacted[index] = compactDocument(item)
+		}
+		return compacted
+	default:
+		return value
+	}
+}
+
+func isEmptyValue(value any) bool {
+	switch typed := value.(type) {
+	case map[string]any:
+		return len(typed) == 0
+	case []any:
+		return len(typed) == 0
+	case string:
+		return typed == ""
+	case bool:
+		return !typed
+	case int:
+		return typed == 0
+	case float64:
+		return typed == 0
+	default:
+		return false
+	}
+}
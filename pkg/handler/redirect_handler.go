@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	apicontext "github.com/softwareplace/goserve/context"
@@ -14,50 +16,64 @@ import (
 	"time"
 )
 
-func requestRedirectHandler(
+func (s *Server) requestRedirectHandler(
 	ctx *apicontext.Request[*apicontext.DefaultContext],
 	redirect model.RedirectConfig,
 ) bool {
-	var request http.Request
-	request = *ctx.Request
-
-	for key, value := range redirect.Headers {
-		request.Header.Set(key, fmt.Sprintf("%v", value))
-	}
+	requestBody := readRequestBody(ctx)
 
+	request := ctx.Request
 	requestedUri := request.URL.RequestURI()
-	targetUri := requestedUri
 
-	replacement := redirect.Replacement
-	if len(replacement) > 0 {
-		for _, replace := range replacement {
-			targetUri = strings.ReplaceAll(targetUri, replace.Old, replace.New)
-		}
+	// The replacements apply to the path only, so the query string is forwarded as it was received
+	targetPath := request.URL.EscapedPath()
+	for _, replace := range redirect.Replacement {
+		targetPath = strings.ReplaceAll(targetPath, replace.Old, replace.New)
+	}
+	targetPath = strings.ReplaceAll(targetPath, "//", "/")
+
+	targetUri := targetPath
+	if request.URL.RawQuery != "" {
+		targetUri += "?" + request.URL.RawQuery
 	}
-	targetUri = strings.ReplaceAll(targetUri, "//", "/")
 
 	targetURL := strings.TrimSuffix(redirect.Url, "/") + "/" +
 		strings.TrimPrefix(targetUri, "/")
 
-	req, err := http.NewRequest(request.Method, targetURL, request.Body)
+	req, err := http.NewRequestWithContext(request.Context(), request.Method, targetURL, bytes.NewReader(requestBody))
 
 	if err != nil {
 		ctx.Error("Failed to complete the request", http.StatusInternalServerError)
 		return true
 	}
-	for key, value := range redirect.Headers {
-		req.Header.Set(key, fmt.Sprintf("%v", value))
+	forwardRequestHeaders(request, req, redirect)
+
+	var breaker *circuitBreaker
+	if redirect.CircuitBreaker != nil {
+		breaker = s.upstreams.Breaker(redirect.Url)
+		if !breaker.Allow() {
+			writeCircuitOpen(ctx, *redirect.CircuitBreaker, redirect.Url)
+			return true
+		}
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.upstreams.Do(req, requestBody, redirect)
+
+	// The requests given up by the client say nothing about the upstream
+	if breaker != nil && request.Context().Err() == nil {
+		breaker.Record(err != nil || resp.StatusCode >= http.StatusInternalServerError, *redirect.CircuitBreaker, redirect.Url)
+	}
 
 	if err != nil {
-		ctx.Error(err.Error(), http.StatusInternalServerError)
+		log.Errorf("Failed to redirect %s -> %s: %v", requestedUri, targetURL, err)
+		if errors.Is(err, errUpstreamTimeout) {
+			ctx.Error(err.Error(), http.StatusGatewayTimeout)
+		} else {
+			ctx.Error(err.Error(), http.StatusBadGateway)
+		}
 		return true
 	}
 
-	// Read the response body
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
@@ -65,26 +81,33 @@ func requestRedirectHandler(
 		}
 	}(resp.Body)
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-
-	if err != nil {
-		ctx.Error(err.Error(), http.StatusInternalServerError)
-		return true
-	}
+	rewriteResponseHeaders(resp.Header, redirect.ResponseHeaders)
 
 	writer := *ctx.Writer
-	responseContentType := resp.Header.Get("Content-Type")
+	copyResponseHeaders(writer.Header(), resp.Header)
+	writer.WriteHeader(resp.StatusCode)
 
-	if responseContentType != "" {
-		writer.Header().Set("Content-Type", responseContentType)
+	// The body is streamed to the client as it is received, and only kept when it is logged or stored afterward
+	var received bytes.Buffer
+	var body io.Reader = resp.Body
+	if redirect.LogEnabled || redirect.StoreResponsesDir != "" || isRecording(redirect) {
+		body = io.TeeReader(resp.Body, &received)
 	}
 
-	writer.WriteHeader(resp.StatusCode)
+	if _, err = io.Copy(newFlushWriter(writer), body); err != nil {
+		log.Errorf("Failed to stream the response of %s -> %s: %v", requestedUri, targetURL, err)
+		return true
+	}
 
-	_, err = writer.Write(bodyBytes)
+	bodyBytes := received.Bytes()
+	responseContentType := resp.Header.Get("Content-Type")
 
 	if redirect.LogEnabled {
-		log.Infof("%s -> %s returned: %s", requestedUri, targetUri, string(bodyBytes))
+		log.Infof("%s -> %s returned: %s", requestedUri, targetURL, string(bodyBytes))
+	}
+
+	if isRecording(redirect) {
+		s.recordResponse(ctx, *redirect.Record, requestBody, resp, bodyBytes)
 	}
 
 	if redirect.StoreResponsesDir != "" {
@@ -103,16 +126,53 @@ func requestRedirectHandler(
 			log.Errorf("Failed to store response: %v", err)
 		})
 		storeFile(data, redirect, requestedUri)
+	}
 
-		return true
+	return true
+}
+
+// writeCircuitOpen answers the request without reaching the upstream, whose circuit is open.
+func writeCircuitOpen(ctx *apicontext.Request[*apicontext.DefaultContext], config model.BreakerConfig, url string) {
+	statusCode := config.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusServiceUnavailable
 	}
 
-	if err != nil {
-		log.Errorf("Failed to write response body: %v", err)
-		return false
+	if config.Body == nil {
+		ctx.Error(fmt.Sprintf("The upstream %s is unavailable", url), statusCode)
+		return
 	}
 
-	return true
+	writer := *ctx.Writer
+	contentType := config.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	writer.Header().Set("Content-Type", contentType)
+	for key, value := range config.Headers {
+		writer.Header().Set(key, fmt.Sprintf("%v", value))
+	}
+	writeBody(ctx, config.Body, statusCode, "", 0)
+}
+
+// flushWriter flushes every write to the client, so the streamed responses, such as server-sent events, are
+// received as soon as the upstream sends them.
+type flushWriter struct {
+	writer  io.Writer
+	flusher http.Flusher
+}
+
+func newFlushWriter(writer http.ResponseWriter) io.Writer {
+	flusher, _ := writer.(http.Flusher)
+	return &flushWriter{writer: writer, flusher: flusher}
+}
+
+func (w *flushWriter) Write(data []byte) (int, error) {
+	written, err := w.writer.Write(data)
+	if err == nil && w.flusher != nil {
+		w.flusher.Flush()
+	}
+	return written, err
 }
 
 func storeFile(data map[string]interface{}, redirect model.RedirectConfig, requestedUri string) {
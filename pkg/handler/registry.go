@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/http"
+	"sync/atomic"
+)
+
+// snapshot is the set of mocks loaded at a point in time, along with the router of their routes.
+// It is never modified once published, so a request keeps a consistent view of the mocks even
+// when they are reloaded meanwhile.
+type snapshot struct {
+	mocks  []model.MockConfigResponse
+	router http.Handler
+}
+
+// mock returns the mock of the snapshot with the given id.
+func (s *snapshot) mock(id string) (model.MockConfigResponse, bool) {
+	for _, mock := range s.mocks {
+		if mock.Id == id {
+			return mock, true
+		}
+	}
+	return model.MockConfigResponse{}, false
+}
+
+// registry holds the current snapshot of the mocks, replaced as a whole on every reload.
+type registry struct {
+	current atomic.Pointer[snapshot]
+}
+
+func newRegistry() *registry {
+	r := &registry{}
+	r.current.Store(&snapshot{router: http.NotFoundHandler()})
+	return r
+}
+
+// Snapshot returns the current snapshot, to read once per request.
+func (r *registry) Snapshot() *snapshot {
+	return r.current.Load()
+}
+
+// Publish replaces the current snapshot with the given mocks and their router, which must not be modified afterward.
+func (r *registry) Publish(mocks []model.MockConfigResponse, router http.Handler) {
+	r.current.Store(&snapshot{mocks: mocks, router: router})
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"io"
+	"strings"
+)
+
+// readRequestBody reads the whole request body and restores it, so it can still be consumed
+// later on, for instance, when the request ends up being redirected.
+func readRequestBody(ctx *apicontext.Request[*apicontext.DefaultContext]) []byte {
+	if ctx.Request.Body == nil {
+		return nil
+	}
+
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		log.Errorf("Failed to read request body: %v", err)
+	}
+
+	_ = ctx.Request.Body.Close()
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(data))
+	return data
+}
+
+// peekRequestBody reads up to limit bytes of the request body and restores it, without reading the rest
+// of it, so a large body is not held in memory. It tells whether the body is longer than the returned bytes.
+func peekRequestBody(ctx *apicontext.Request[*apicontext.DefaultContext], limit int) ([]byte, bool) {
+	if ctx.Request.Body == nil || limit <= 0 {
+		return nil, ctx.Request.ContentLength > 0
+	}
+
+	data, err := io.ReadAll(io.LimitReader(ctx.Request.Body, int64(limit)+1))
+	if err != nil {
+		log.Errorf("Failed to read request body: %v", err)
+	}
+
+	body := ctx.Request.Body
+	ctx.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), body), body}
+
+	if len(data) > limit {
+		return data[:limit], true
+	}
+	return data, false
+}
+
+// parseRequestBody returns the request body decoded as JSON when the content type says so,
+// falling back to the raw string otherwise.
+func parseRequestBody(contentType string, data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+
+	if strings.Contains(contentType, "json") {
+		var value any
+		if err := json.Unmarshal(data, &value); err == nil {
+			return value
+		}
+	}
+
+	return string(data)
+}
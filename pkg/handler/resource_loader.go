@@ -10,24 +10,40 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 )
 
-func LoadResponses(onFileChangeDetected OnFileChangDetected) {
-	loadMockResponses()
+// LoadResponses loads the mocks, then watches their files to reload them on change.
+func (s *Server) LoadResponses(onFileChangeDetected OnFileChangDetected) {
+	s.reloadMu.Lock()
+	s.onReload = onFileChangeDetected
+	s.reloadMu.Unlock()
+
+	s.loadMockResponses()
+
+	// The files are watched before the callback, so the changes made right after the load are reloaded
+	ready := make(chan struct{})
 	go func() {
-		watchAndReload(onFileChangeDetected)
+		s.watchAndReload(onFileChangeDetected, ready)
 	}()
-	time.Sleep(256 * time.Millisecond)
+	<-ready
 	onFileChangeDetected(false)
 }
 
-func loadMockResponses() {
-	mockJsonFilesBasePath := env.GetAppEnv().MockPath
+// loadMockResponses loads the mocks of the mock files, merged with the ones managed at runtime. The loads run
+// one at a time, so a slower load never publishes its stale mocks after a later one.
+func (s *Server) loadMockResponses() {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	mockJsonFilesBasePath := s.env.MockPath
 
 	var newResponses []model.MockConfigResponse
 
 	errohandler.Handler(func() {
+		if mockJsonFilesBasePath == "" {
+			return
+		}
+
 		err := filepath.Walk(mockJsonFilesBasePath, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
@@ -54,6 +70,7 @@ func loadMockResponses() {
 
 				response.Redirect.StoreResponsesDir = env.UserHomePathFix(response.Redirect.StoreResponsesDir)
 				response.MockFilePath = path
+				response.Id = fileMockId(response)
 				newResponses = append(newResponses, response)
 			}
 			return nil
@@ -66,5 +83,5 @@ func loadMockResponses() {
 		log.Errorf("Failed to load mock files: %v", err)
 	})
 
-	model.MockConfigResponses = newResponses
+	s.publish(s.runtimeMocks.Merge(newResponses))
 }
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// writeBody writes the body to the response, whose Content-Type header is expected to be set already.
+//
+// String bodies are written as they are, so raw text, XML, HTML, CSV and even JSON documents can be
+// provided as a plain string. Base64 encoded bodies are decoded before being written, which allows
+// binary content. Any other body is serialized as JSON. The body is throttled to the given bytes per
+// second, unless it is zero.
+func writeBody(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	body *interface{},
+	status int,
+	encoding string,
+	bytesPerSecond int,
+) {
+	if ctx.Completed {
+		return
+	}
+	defer ctx.Done()
+
+	writer := *ctx.Writer
+
+	data, err := encodeBody(body, encoding)
+	if err != nil {
+		log.Errorf("Failed to encode response body: %v", err)
+		ctx.Error("Failed to encode response body", http.StatusInternalServerError)
+		return
+	}
+
+	if data != nil {
+		writer.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	}
+	writer.WriteHeader(status)
+
+	if _, err = newThrottledWriter(writer, bytesPerSecond).Write(data); err != nil {
+		log.Errorf("Failed to write response body: %v", err)
+	}
+}
+
+func encodeBody(body *interface{}, encoding string) ([]byte, error) {
+	if body == nil || *body == nil {
+		return nil, nil
+	}
+
+	if text, isString := (*body).(string); isString {
+		if strings.EqualFold(encoding, model.EncodingBase64) {
+			return base64.StdEncoding.DecodeString(text)
+		}
+		return []byte(text), nil
+	}
+
+	if encoding != "" {
+		return nil, fmt.Errorf("the %s encoding requires a string body", encoding)
+	}
+
+	return json.Marshal(*body)
+}
+
+// writeBodyFile streams the file as the response body, along with its Content-Length. The file is
+// throttled to the given bytes per second, unless it is zero.
+func writeBodyFile(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	filePath string,
+	status int,
+	bytesPerSecond int,
+) {
+	if ctx.Completed {
+		return
+	}
+	defer ctx.Done()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Errorf("Failed to open body file %s: %v", filePath, err)
+		ctx.Error("Failed to read the body file", http.StatusInternalServerError)
+		return
+	}
+
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			log.Errorf("Failed to close body file %s: %v", filePath, err)
+		}
+	}(file)
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		log.Errorf("Invalid body file %s: %v", filePath, err)
+		ctx.Error("Failed to read the body file", http.StatusInternalServerError)
+		return
+	}
+
+	writer := *ctx.Writer
+	writer.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	writer.WriteHeader(status)
+
+	if _, err = io.Copy(newThrottledWriter(writer, bytesPerSecond), file); err != nil {
+		log.Errorf("Failed to stream body file %s: %v", filePath, err)
+	}
+}
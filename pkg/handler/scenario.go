@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/http"
+	"sync"
+)
+
+// scenarioStore keeps the current state of each scenario. A scenario that was never
+// moved to another state is in the model.ScenarioStateStarted state.
+type scenarioStore struct {
+	mu     sync.RWMutex
+	states map[string]string
+}
+
+// State returns the current state of the scenario.
+func (s *scenarioStore) State(name string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if state, found := s.states[name]; found {
+		return state
+	}
+	return model.ScenarioStateStarted
+}
+
+// Set moves the scenario to the given state.
+func (s *scenarioStore) Set(name string, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[name] = state
+}
+
+// Reset moves every scenario back to the model.ScenarioStateStarted state.
+func (s *scenarioStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states = make(map[string]string)
+}
+
+// All returns the current state of every scenario, including the ones declared by the mock responses.
+func (s *scenarioStore) All(mocks []model.MockConfigResponse) map[string]string {
+	states := make(map[string]string)
+	for _, config := range mocks {
+		for _, body := range config.Response.Bodies {
+			if body.Scenario != "" {
+				states[body.Scenario] = s.State(body.Scenario)
+			}
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for name, state := range s.states {
+		states[name] = state
+	}
+	return states
+}
+
+// matchScenario checks if the scenario of the body is in the required state.
+func (s *Server) matchScenario(body model.ResponseBody) (int, error) {
+	if body.Scenario == "" || body.RequiredState == "" {
+		return 0, nil
+	}
+
+	state := s.scenarios.State(body.Scenario)
+	if state != body.RequiredState {
+		return 0, fmt.Errorf("scenario %q is in state %q, expected %q", body.Scenario, state, body.RequiredState)
+	}
+	return scoreExact, nil
+}
+
+// moveScenario moves the scenario of the served body to its new state, if any.
+func (s *Server) moveScenario(body *model.ResponseBody) {
+	if body.Scenario != "" && body.NewState != "" {
+		log.Infof("Scenario %s moved to state %s", body.Scenario, body.NewState)
+		s.scenarios.Set(body.Scenario, body.NewState)
+	}
+}
+
+type scenarioStateRequest struct {
+	State string `json:"state"`
+}
+
+func (s *Server) registerScenarioHandlers(appServer server.Api[*apicontext.DefaultContext]) {
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		ctx.Ok(s.scenarios.All(s.Mocks()))
+	}, AdminPath+"/scenarios", http.MethodGet)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		s.scenarios.Reset()
+		log.Infof("All scenarios were reset")
+		ctx.Ok(s.scenarios.All(s.Mocks()))
+	}, AdminPath+"/scenarios/reset", http.MethodPost)
+
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		var request scenarioStateRequest
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&request); err != nil || request.State == "" {
+			ctx.BadRequest("The request body must provide the state, e.g. {\"state\": \"Started\"}")
+			return
+		}
+
+		name := ctx.PathValues["name"]
+		s.scenarios.Set(name, request.State)
+		log.Infof("Scenario %s set to state %s", name, request.State)
+		ctx.Ok(s.scenarios.All(s.Mocks()))
+	}, AdminPath+"/scenarios/{name}/state", http.MethodPut)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	log "github.com/sirupsen/logrus"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"math/rand"
+	"net/http"
+	"sync"
+)
+
+// selectionStore keeps how many times each mock response selected a body using a strategy, along with
+// the random source of the weighted-random strategy.
+type selectionStore struct {
+	mu       sync.Mutex
+	counters map[string]int
+	seed     int64
+	random   *rand.Rand
+}
+
+func newSelectionStore(seed int64) *selectionStore {
+	return &selectionStore{counters: make(map[string]int), seed: seed, random: newRandom(seed)}
+}
+
+// Next returns the current counter of the mock response and increments it.
+func (s *selectionStore) Next(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := s.counters[key]
+	s.counters[key] = counter + 1
+	return counter
+}
+
+// Reset restarts the counter of every mock response, and reseeds the random source so the weighted-random
+// selection starts over.
+func (s *selectionStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counters = make(map[string]int)
+	s.random = newRandom(s.seed)
+}
+
+// Random returns the random source of the weighted-random strategy.
+func (s *selectionStore) Random() *rand.Rand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.random
+}
+
+// selectBody chooses the body to return among the matching ones according to the response strategy.
+func (s *Server) selectBody(config model.MockConfigResponse, matches []bodyMatch) *model.ResponseBody {
+	var candidates []*model.ResponseBody
+	for _, match := range matches {
+		if match.err == nil {
+			candidates = append(candidates, match.body)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	key := selectionKey(config)
+
+	switch config.Response.Strategy {
+	case model.StrategySequence:
+		index := s.selections.Next(key)
+		if index >= len(candidates) {
+			if config.Response.Loop {
+				index %= len(candidates)
+			} else {
+				index = len(candidates) - 1
+			}
+		}
+		return candidates[index]
+	case model.StrategyRoundRobin:
+		return candidates[s.selections.Next(key)%len(candidates)]
+	case model.StrategyWeightedRandom:
+		return selectWeightedRandom(candidates, s.selections.Random())
+	default:
+		log.Warnf("Unknown strategy %q on %s, returning the first matching body", config.Response.Strategy, config.MockFilePath)
+		return candidates[0]
+	}
+}
+
+func selectWeightedRandom(candidates []*model.ResponseBody, random *rand.Rand) *model.ResponseBody {
+	total := 0
+	for _, candidate := range candidates {
+		total += bodyWeight(candidate)
+	}
+
+	target := random.Intn(total)
+	for _, candidate := range candidates {
+		target -= bodyWeight(candidate)
+		if target < 0 {
+			return candidate
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+func bodyWeight(body *model.ResponseBody) int {
+	if body.Weight <= 0 {
+		return 1
+	}
+	return body.Weight
+}
+
+// selectionKey identifies the mock response by its id, which is unique across the file and runtime mocks.
+func selectionKey(config model.MockConfigResponse) string {
+	return config.Id
+}
+
+func (s *Server) registerSelectionHandlers(appServer server.Api[*apicontext.DefaultContext]) {
+	appServer.Add(func(ctx *apicontext.Request[*apicontext.DefaultContext]) {
+		s.selections.Reset()
+		log.Infof("All sequences were reset")
+		ctx.NoContent(nil)
+	}, AdminPath+"/sequences/reset", http.MethodPost)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"fmt"
+	apicontext "github.com/softwareplace/goserve/context"
+	"github.com/softwareplace/goserve/server"
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/http"
+	"sync"
+)
+
+// Server holds the state of a mock server: its settings, the loaded mocks, and the state managed at
+// runtime through the admin endpoints. Each Server is independent, so several of them can run side by side.
+type Server struct {
+	env          *env.AppEnv
+	config       *model.MockServerConfig
+	registry     *registry
+	scenarios    *scenarioStore
+	selections   *selectionStore
+	chaos        *chaosStore
+	runtimeMocks *mockStore
+	journal      *journalStore
+	upstreams    *upstreamStore
+	onReload     OnFileChangDetected
+	loadMu       sync.Mutex
+	reloadMu     sync.Mutex
+	recordMu     sync.Mutex
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// NewServer returns a mock server with the given settings. The config is optional and provides the
+// settings of the configuration file, such as the global redirect, latency and chaos.
+func NewServer(appEnv *env.AppEnv, config *model.MockServerConfig) *Server {
+	if config == nil {
+		config = &model.MockServerConfig{}
+	}
+
+	s := &Server{
+		env:          appEnv,
+		config:       config,
+		registry:     newRegistry(),
+		scenarios:    &scenarioStore{states: make(map[string]string)},
+		selections:   newSelectionStore(config.Seed),
+		chaos:        newChaosStore(config.Chaos),
+		runtimeMocks: &mockStore{hidden: make(map[string]bool)},
+		journal:      &journalStore{size: journalSize(config), bodySize: journalBodySize(config)},
+		upstreams:    newUpstreamStore(),
+		done:         make(chan struct{}),
+	}
+	s.publish(nil)
+	return s
+}
+
+var (
+	defaultServer     *Server
+	defaultServerOnce sync.Once
+)
+
+// Default returns the mock server configured by the command line flags and the configuration file.
+func Default() *Server {
+	defaultServerOnce.Do(func() {
+		appEnv := env.GetAppEnv()
+		defaultServer = NewServer(appEnv, appEnv.Config)
+	})
+	return defaultServer
+}
+
+// Register registers the admin endpoints and the mocks of the Default server.
+func Register(appServer server.Api[*apicontext.DefaultContext]) {
+	Default().Register(appServer)
+}
+
+// LoadResponses loads the mocks of the Default server and watches their files.
+func LoadResponses(onFileChangeDetected OnFileChangDetected) {
+	Default().LoadResponses(onFileChangeDetected)
+}
+
+// NotFound answers the requests no mock of the Default server is registered for.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Default().NotFound(w, r)
+}
+
+// Handler returns the handler serving the current mocks. The reloaded mocks are served by it as soon
+// as they are loaded, so the server using it never has to be restarted, and the requests in flight
+// complete with the mocks they started with.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		s.registry.Snapshot().router.ServeHTTP(writer, request)
+	})
+}
+
+// publish builds the routes of the mocks, then makes them the current ones.
+func (s *Server) publish(mocks []model.MockConfigResponse) {
+	router := server.Default().
+		ContextPath(s.env.ContextPath).
+		EmbeddedServer(func(appServer server.Api[*apicontext.DefaultContext]) {
+			s.register(appServer, mocks)
+		}).
+		CustomNotFoundHandler(s.NotFound)
+	s.registry.Publish(mocks, router)
+}
+
+// Mocks returns the loaded mocks, the ones of the mock files merged with the ones managed at runtime.
+// The returned mocks are shared by the requests being served, and must not be modified.
+func (s *Server) Mocks() []model.MockConfigResponse {
+	return s.registry.Snapshot().mocks
+}
+
+// AddMock adds a mock managed at runtime, with a random id unless provided, then reloads the server
+// before returning. A mock with the same id is replaced.
+func (s *Server) AddMock(mock model.MockConfigResponse) (model.MockConfigResponse, error) {
+	if err := validateMock(mock); err != nil {
+		return mock, err
+	}
+
+	if mock.Id == "" {
+		mock.Id = newMockId()
+	}
+
+	s.runtimeMocks.Put(mock)
+	s.reloadMocks(false)
+
+	added, found := s.findMock(mock.Id)
+	if !found {
+		return mock, fmt.Errorf("the mock %s could not be loaded", mock.Id)
+	}
+	return added, nil
+}
+
+// ResetMocks removes every mock managed at runtime, then reloads the server before returning.
+func (s *Server) ResetMocks() {
+	s.runtimeMocks.Reset()
+	s.reloadMocks(false)
+}
+
+// Close stops watching the mock files and closes the idle connections to the redirect upstreams.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.upstreams.CloseIdleConnections()
+	})
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	apicontext "github.com/softwareplace/goserve/context"
+	"math/rand"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is the data exposed to the response templates.
+//
+// Example:
+//
+//	body:
+//	  id: "{{ .Path.id }}"
+//	  page: "{{ .Query.page | default \"1\" }}"
+//	  agent: "{{ index .Headers \"user-agent\" }}"
+//	  name: "{{ .Body.name }}"
+type templateData struct {
+	Method  string              // Method is the HTTP method of the incoming request.
+	Url     string              // Url is the requested URI, including the query string.
+	Path    map[string]string   // Path contains the path values extracted from the request path.
+	Query   map[string]string   // Query contains the first value of each query parameter.
+	Queries map[string][]string // Queries contains all the values of each query parameter.
+	Headers map[string]string   // Headers contains the first value of each request header, keyed by its lower case name.
+	Body    any                 // Body is the request body, decoded as JSON when possible or the raw string otherwise.
+}
+
+var templateFuncs = template.FuncMap{
+	"now": func(layout ...string) string {
+		if len(layout) > 0 {
+			return time.Now().Format(layout[0])
+		}
+		return time.Now().Format(time.RFC3339)
+	},
+	"unix": func() int64 {
+		return time.Now().Unix()
+	},
+	"uuid": func() string {
+		return uuid.NewString()
+	},
+	"randomInt": func(min, max int) int {
+		if max <= min {
+			return min
+		}
+		return min + rand.Intn(max-min)
+	},
+	"default": func(defaultValue any, value any) any {
+		if value == nil || fmt.Sprintf("%v", value) == "" {
+			return defaultValue
+		}
+		return value
+	},
+	"toJson": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+func newTemplateData(ctx *apicontext.Request[*apicontext.DefaultContext]) templateData {
+	data := templateData{
+		Method:  ctx.Request.Method,
+		Url:     ctx.Request.URL.RequestURI(),
+		Path:    ctx.PathValues,
+		Query:   make(map[string]string),
+		Queries: ctx.QueryValues,
+		Headers: make(map[string]string),
+	}
+
+	for key, values := range ctx.QueryValues {
+		if len(values) > 0 {
+			data.Query[key] = values[0]
+		}
+	}
+
+	for key, values := range ctx.Request.Header {
+		if len(values) > 0 {
+			data.Headers[strings.ToLower(key)] = values[0]
+		}
+	}
+
+	data.Body = parseRequestBody(ctx.Request.Header.Get("Content-Type"), readRequestBody(ctx))
+	return data
+}
+
+// renderTemplates returns a copy of the given body and headers with every string value rendered
+// as a Go template using the incoming request data.
+func renderTemplates(
+	ctx *apicontext.Request[*apicontext.DefaultContext],
+	body *interface{},
+	headers *map[string]any,
+) (*interface{}, *map[string]any, error) {
+	data := newTemplateData(ctx)
+
+	var renderedBody *interface{}
+	if body != nil {
+		value, err := renderValue(*body, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		renderedBody = &value
+	}
+
+	var renderedHeaders *map[string]any
+	if headers != nil {
+		values := make(map[string]any, len(*headers))
+		for key, value := range *headers {
+			rendered, err := renderString(fmt.Sprintf("%v", value), data)
+			if err != nil {
+				return nil, nil, err
+			}
+			values[key] = rendered
+		}
+		renderedHeaders = &values
+	}
+
+	return renderedBody, renderedHeaders, nil
+}
+
+func renderValue(value any, data templateData) (any, error) {
+	switch typed := value.(type) {
+	case string:
+		return renderString(typed, data)
+	case map[string]any:
+		rendered := make(map[string]any, len(typed))
+		for key, item := range typed {
+			renderedKey, err := renderString(key, data)
+			if err != nil {
+				return nil, err
+			}
+			renderedItem, err := renderValue(item, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered[renderedKey] = renderedItem
+		}
+		return rendered, nil
+	case []any:
+		rendered := make([]any, len(typed))
+		for index, item := range typed {
+			renderedItem, err := renderValue(item, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered[index] = renderedItem
+		}
+		return rendered, nil
+	default:
+		return value, nil
+	}
+}
+
+func renderString(value string, data templateData) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	tmpl, err := template.New("response").
+		Option("missingkey=zero").
+		Funcs(templateFuncs).
+		Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %q: %w", value, err)
+	}
+
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return "", fmt.Errorf("failed to execute template %q: %w", value, err)
+	}
+
+	return buffer.String(), nil
+}
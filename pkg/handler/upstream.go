@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultUpstreamTimeout     = 30 * time.Second
+	defaultRetryBackoff        = 100 * time.Millisecond
+	defaultBreakerThreshold    = 5
+	defaultBreakerOpenDuration = 30 * time.Second
+)
+
+var defaultRetryStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// errUpstreamTimeout is returned when the upstream does not reply within the timeout of the redirect.
+var errUpstreamTimeout = errors.New("the upstream did not reply in time")
+
+// upstreamStore keeps the clients of the redirects, sharing their connections across the requests,
+// along with the circuit breaker of each upstream.
+type upstreamStore struct {
+	mu       sync.Mutex
+	clients  map[string]*http.Client
+	breakers map[string]*circuitBreaker
+}
+
+func newUpstreamStore() *upstreamStore {
+	return &upstreamStore{
+		clients:  make(map[string]*http.Client),
+		breakers: make(map[string]*circuitBreaker),
+	}
+}
+
+// Client returns the client of the connection pool configuration, created on first use.
+func (u *upstreamStore) Client(pool *model.PoolConfig) *http.Client {
+	key, _ := json.Marshal(pool)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if client, found := u.clients[string(key)]; found {
+		return client
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if pool != nil {
+		if pool.MaxIdleConns > 0 {
+			transport.MaxIdleConns = pool.MaxIdleConns
+		}
+		if pool.MaxIdleConnsPerHost > 0 {
+			transport.MaxIdleConnsPerHost = pool.MaxIdleConnsPerHost
+		}
+		if pool.MaxConnsPerHost > 0 {
+			transport.MaxConnsPerHost = pool.MaxConnsPerHost
+		}
+		if pool.IdleConnTimeout > 0 {
+			transport.IdleConnTimeout = time.Duration(pool.IdleConnTimeout) * time.Millisecond
+		}
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		// The redirects of the upstream are returned to the client, which follows them through the mock server
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	u.clients[string(key)] = client
+	return client
+}
+
+// Breaker returns the circuit breaker of the upstream URL, created on first use.
+func (u *upstreamStore) Breaker(url string) *circuitBreaker {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	breaker, found := u.breakers[url]
+	if !found {
+		breaker = &circuitBreaker{}
+		u.breakers[url] = breaker
+	}
+	return breaker
+}
+
+// CloseIdleConnections closes the connections kept open to the upstreams.
+func (u *upstreamStore) CloseIdleConnections() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, client := range u.clients {
+		client.CloseIdleConnections()
+	}
+}
+
+// Do sends the request to the upstream, retrying it as configured by the redirect. The body is sent
+// again on every attempt, and the response of the last attempt is returned.
+func (u *upstreamStore) Do(req *http.Request, body []byte, redirect model.RedirectConfig) (*http.Response, error) {
+	client := u.Client(redirect.Pool)
+	timeout := upstreamTimeout(redirect)
+
+	attempts, backoff, maxBackoff, statusCodes := 0, defaultRetryBackoff, time.Duration(0), defaultRetryStatusCodes
+	if retry := redirect.Retry; retry != nil {
+		attempts = retry.Attempts
+		if retry.Backoff > 0 {
+			backoff = time.Duration(retry.Backoff) * time.Millisecond
+		}
+		maxBackoff = time.Duration(retry.MaxBackoff) * time.Millisecond
+		if len(retry.StatusCodes) > 0 {
+			statusCodes = retry.StatusCodes
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(req.Context())
+		if len(body) > 0 {
+			attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := sendWithTimeout(client, attemptReq, timeout, redirect.Timeout != 0)
+		retryable := err != nil || slices.Contains(statusCodes, resp.StatusCode)
+		if attempt >= attempts || !retryable || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if err != nil {
+			log.Warnf("Retrying %s %s in %v after error: %v", req.Method, req.URL, backoff, err)
+		} else {
+			log.Warnf("Retrying %s %s in %v after status %d", req.Method, req.URL, backoff, resp.StatusCode)
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+			_ = resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if maxBackoff > 0 && backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func upstreamTimeout(redirect model.RedirectConfig) time.Duration {
+	if redirect.Timeout == 0 {
+		return defaultUpstreamTimeout
+	}
+	return time.Duration(redirect.Timeout) * time.Millisecond
+}
+
+// sendWithTimeout sends the request, canceling it when the upstream does not send the response headers,
+// or any part of the body, within the timeout. A zero or negative timeout never cancels it. Unless the
+// timeout is explicit, the body of a streamed response is not timed, as it may stay idle for long.
+func sendWithTimeout(client *http.Client, req *http.Request, timeout time.Duration, explicit bool) (*http.Response, error) {
+	if timeout <= 0 {
+		return client.Do(req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	body := &timeoutBody{timeout: timeout, cancel: cancel}
+	body.timer = time.AfterFunc(timeout, func() {
+		body.timedOut.Store(true)
+		cancel()
+	})
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		body.timer.Stop()
+		cancel()
+		if body.timedOut.Load() {
+			return nil, fmt.Errorf("%w after %v", errUpstreamTimeout, timeout)
+		}
+		return nil, err
+	}
+
+	body.ReadCloser = resp.Body
+	resp.Body = body
+	if !explicit && isStreamedResponse(resp) {
+		body.streamed = true
+		body.timer.Stop()
+	}
+	return resp, nil
+}
+
+// isStreamedResponse checks if the response is streamed, such as server-sent events or a chunked body
+// sent as a long poll.
+func isStreamedResponse(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream" || slices.Contains(resp.TransferEncoding, "chunked")
+}
+
+// timeoutBody cancels the upstream request when no part of its body is received within the timeout,
+// unless the body is streamed.
+type timeoutBody struct {
+	io.ReadCloser
+	timer    *time.Timer
+	timeout  time.Duration
+	cancel   context.CancelFunc
+	timedOut atomic.Bool
+	streamed bool
+}
+
+func (b *timeoutBody) Read(data []byte) (int, error) {
+	read, err := b.ReadCloser.Read(data)
+	if err != nil && err != io.EOF && b.timedOut.Load() {
+		return read, fmt.Errorf("%w after %v", errUpstreamTimeout, b.timeout)
+	}
+	if !b.streamed {
+		b.timer.Reset(b.timeout)
+	}
+	return read, err
+}
+
+func (b *timeoutBody) Close() error {
+	b.timer.Stop()
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// circuitBreaker counts the consecutive failures of an upstream, to fail fast once it keeps failing.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// Allow checks if a request can be sent to the upstream, which is the case unless the circuit is open.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !time.Now().Before(b.openUntil)
+}
+
+// Record counts the outcome of a request, opening the circuit once the failures reach the threshold.
+func (b *circuitBreaker) Record(failed bool, config model.BreakerConfig, url string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	threshold := config.Threshold
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+	openDuration := defaultBreakerOpenDuration
+	if config.OpenDuration > 0 {
+		openDuration = time.Duration(config.OpenDuration) * time.Millisecond
+	}
+
+	b.failures++
+	if b.failures >= threshold {
+		b.openUntil = time.Now().Add(openDuration)
+		log.Warnf("Circuit opened for %s after %d consecutive failures, failing fast for %v", url, b.failures, openDuration)
+	}
+}
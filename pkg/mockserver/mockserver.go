@@ -0,0 +1,108 @@
+// Package mockserver embeds the mock server in Go tests. Each MockServer has its own mocks, scenarios,
+// journal and chaos state, so several of them can run in parallel in the same test binary.
+//
+//	server := mockserver.New(mockserver.Options{MockPath: "./dev/mock"}).Start()
+//	defer server.Close()
+//
+//	_, err := server.Stub(http.MethodGet, "/api/users/{id}").
+//		WithPathValue("id", "1").
+//		WillReturn(http.StatusOK, map[string]any{"id": 1, "name": "John"}).
+//		Register()
+//
+//	response, err := http.Get(server.URL() + "/api/users/1")
+package mockserver
+
+import (
+	"github.com/softwareplace/mock-server/pkg/env"
+	"github.com/softwareplace/mock-server/pkg/handler"
+	"github.com/softwareplace/mock-server/pkg/model"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Options configures a MockServer. Every option is optional.
+type Options struct {
+	MockPath    string                  // MockPath is the directory of the mock files to load and watch. No mock file is loaded when empty.
+	ContextPath string                  // ContextPath is the base path of every route, "/" by default.
+	Config      *model.MockServerConfig // Config provides the settings of a configuration file, such as the global redirect, latency and chaos.
+	Debug       bool                    // Debug answers the unmatched requests with the mocks closest to them and why they do not match.
+}
+
+// MockServer is a mock server embedded in a Go program, served by an httptest server once started.
+type MockServer struct {
+	handler *handler.Server
+	mu      sync.Mutex
+	server  *httptest.Server
+	stubMu  sync.Mutex
+}
+
+// New returns a MockServer with the mocks of the Options.MockPath loaded, ready to be started or used as an http.Handler.
+func New(options Options) *MockServer {
+	if options.ContextPath == "" {
+		options.ContextPath = "/"
+	}
+
+	appEnv := &env.AppEnv{
+		MockPath:    options.MockPath,
+		ContextPath: options.ContextPath,
+		Debug:       options.Debug,
+	}
+
+	mockServer := &MockServer{handler: handler.NewServer(appEnv, options.Config)}
+	mockServer.handler.LoadResponses(func(restartServer bool) {})
+	return mockServer
+}
+
+// Start serves the MockServer on a random local port, see URL.
+func (m *MockServer) Start() *MockServer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.server == nil {
+		m.server = httptest.NewServer(m.Handler())
+	}
+	return m
+}
+
+// URL returns the base URL of the started MockServer, such as http://127.0.0.1:41234, empty when not started.
+func (m *MockServer) URL() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.server == nil {
+		return ""
+	}
+	return m.server.URL
+}
+
+// Handler returns the http.Handler serving the current mocks, to use the MockServer without starting it.
+func (m *MockServer) Handler() http.Handler {
+	return m.handler.Handler()
+}
+
+// Mocks returns the loaded mocks, the ones of the mock files along with the registered stubs.
+func (m *MockServer) Mocks() []model.MockConfigResponse {
+	return m.handler.Mocks()
+}
+
+// AddMock registers a mock, the same way as the admin endpoint, returning it with its id.
+func (m *MockServer) AddMock(mock model.MockConfigResponse) (model.MockConfigResponse, error) {
+	return m.handler.AddMock(mock)
+}
+
+// Reset removes every registered stub and mock, restoring the ones of the mock files.
+func (m *MockServer) Reset() {
+	m.handler.ResetMocks()
+}
+
+// Close stops the MockServer and watching its mock files.
+func (m *MockServer) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.server != nil {
+		m.server.Close()
+	}
+	m.handler.Close()
+}
@@ -0,0 +1,134 @@
+package mockserver
+
+import (
+	"github.com/softwareplace/mock-server/pkg/model"
+	"strings"
+	"time"
+)
+
+// Stub builds a mock response in code, the same way as a mock file, until it is registered.
+type Stub struct {
+	server *MockServer
+	method string
+	path   string
+	body   model.ResponseBody
+}
+
+// Stub starts building a mock response for the method and path, such as /api/users/{id}.
+// It returns a 200 with no body unless WillReturn is called.
+func (m *MockServer) Stub(method string, path string) *Stub {
+	return &Stub{server: m, method: method, path: path}
+}
+
+// matching returns the matching of the stub, which only requires the path values and the query parameters it
+// lists, accepting any other one.
+func (s *Stub) matching() *model.Matching {
+	if s.body.Matching == nil {
+		s.body.Matching = &model.Matching{
+			QueryMode: model.MatchingModeSubset,
+			PathMode:  model.MatchingModeSubset,
+		}
+	}
+	return s.body.Matching
+}
+
+// WithPathValue requires the path variable, such as id in /api/users/{id}, to match the value. Any other path variable is accepted.
+func (s *Stub) WithPathValue(key string, value any) *Stub {
+	matching := s.matching()
+	if matching.Paths == nil {
+		matching.Paths = make(map[string]any)
+	}
+	matching.Paths[key] = value
+	return s
+}
+
+// WithQuery requires the query parameter to match the value. Any other query parameter is accepted.
+func (s *Stub) WithQuery(key string, value any) *Stub {
+	matching := s.matching()
+	if matching.Queries == nil {
+		matching.Queries = make(map[string]any)
+	}
+	matching.Queries[key] = value
+	return s
+}
+
+// WithHeader requires the request header to match the value.
+func (s *Stub) WithHeader(key string, value any) *Stub {
+	matching := s.matching()
+	if matching.Headers == nil {
+		matching.Headers = make(map[string]any)
+	}
+	matching.Headers[key] = value
+	return s
+}
+
+// WithBody requires the request payload to satisfy the criteria.
+func (s *Stub) WithBody(body model.BodyMatching) *Stub {
+	s.matching().Body = &body
+	return s
+}
+
+// WithPriority prefers this stub over the others of the same route when several match the request, the higher the preferred.
+func (s *Stub) WithPriority(priority int) *Stub {
+	s.body.Priority = priority
+	return s
+}
+
+// WillReturn sets the status code and body of the response. The body is written as JSON unless it is a string.
+func (s *Stub) WillReturn(statusCode int, body any) *Stub {
+	s.body.StatusCode = statusCode
+	if body != nil {
+		s.body.Body = &body
+	}
+	return s
+}
+
+// WithResponseHeader adds a header to the response.
+func (s *Stub) WithResponseHeader(key string, value any) *Stub {
+	if s.body.Headers == nil {
+		s.body.Headers = &map[string]any{}
+	}
+	(*s.body.Headers)[key] = value
+	return s
+}
+
+// WithContentType sets the Content-Type of the response, application/json by default.
+func (s *Stub) WithContentType(contentType string) *Stub {
+	s.body.ContentType = contentType
+	return s
+}
+
+// WithDelay waits before sending the response.
+func (s *Stub) WithDelay(delay time.Duration) *Stub {
+	milliseconds := int(delay.Milliseconds())
+	s.body.Delay = &milliseconds
+	return s
+}
+
+// WithFault simulates a network failure instead of the response, such as model.FaultConnectionReset.
+func (s *Stub) WithFault(fault string) *Stub {
+	s.body.Fault = fault
+	return s
+}
+
+// Register adds the stub to the MockServer, returning the id of the mock holding it. A stub for the route of
+// an already loaded mock is added to its bodies, so the stubs of a route are matched against each other.
+func (s *Stub) Register() (string, error) {
+	s.server.stubMu.Lock()
+	defer s.server.stubMu.Unlock()
+
+	mock := model.MockConfigResponse{
+		Request: model.RequestConfig{Method: strings.ToUpper(s.method), Path: s.path},
+	}
+	for _, existing := range s.server.Mocks() {
+		if strings.EqualFold(existing.Request.Method, s.method) && existing.Request.Path == s.path {
+			mock = existing
+			break
+		}
+	}
+
+	mock.Response.Bodies = append(append([]model.ResponseBody{}, mock.Response.Bodies...), s.body)
+
+	registered, err := s.server.AddMock(mock)
+	return registered.Id, err
+}
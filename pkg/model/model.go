@@ -1,12 +1,15 @@
 package model
 
 type MockConfigResponse struct {
-	Request      RequestConfig  `json:"request" yaml:"request"`   // Request contains the configuration details for the HTTP request.
-	Response     ResponseConfig `json:"response" yaml:"response"` // Response holds the specifications for the HTTP response configuration.
-	Redirect     RedirectConfig `json:"redirect" yaml:"redirect"` // Redirect defines the settings for HTTP redirection if applicable.
-	MockFilePath string         // MockFilePath specifies the file path to the mock configuration file used for HTTP request and response simulation.
+	Id           string         `json:"id" yaml:"id,omitempty"`          // Id identifies the mock in the admin endpoints, derived from the MockFilePath unless provided.
+	Request      RequestConfig  `json:"request" yaml:"request"`          // Request contains the configuration details for the HTTP request.
+	Response     ResponseConfig `json:"response" yaml:"response"`        // Response holds the specifications for the HTTP response configuration.
+	Redirect     RedirectConfig `json:"redirect" yaml:"redirect"`        // Redirect defines the settings for HTTP redirection if applicable.
+	MockFilePath string         `json:"mockFilePath,omitempty" yaml:"-"` // MockFilePath specifies the file path to the mock configuration file used for HTTP request and response simulation.
 }
 
+// Replacement rewrites the path of the redirected requests. It only applies to the path, the query string being
+// forwarded as it was received.
 type Replacement struct {
 	Old string `json:"old" yaml:"old"` // Old specifies the string to be replaced during the redirection process.
 	New string `json:"new" yaml:"new"` // New specifies the replacement string for the redirection process.
@@ -15,9 +18,86 @@ type Replacement struct {
 type RedirectConfig struct {
 	Url               string         `json:"url" yaml:"url"`                               // Url specifies the target URL for the redirection.
 	Headers           map[string]any `json:"headers" yaml:"headers"`                       // Headers to provide custom headers when redirect
-	Replacement       []Replacement  `json:"replacement" yaml:"replacement"`               // Replacement specifies a list of string replacements to perform in the redirection process.
+	Replacement       []Replacement  `json:"replacement" yaml:"replacement"`               // Replacement specifies a list of string replacements to perform on the path of the redirected requests.
 	LogEnabled        bool           `json:"logEnabled" yaml:"log-enabled"`                // LogEnabled determines whether logging is enabled for the redirection process response.
 	StoreResponsesDir string         `json:"storeResponsesDir" yaml:"store-responses-dir"` // StoreResponsesDir if provided, store the data from redirected process.
+	Record            *RecordConfig  `json:"record" yaml:"record"`                         // Record writes the redirected requests and their responses as mock files, to replay them offline.
+	ForwardHeaders    *ForwardConfig `json:"forwardHeaders" yaml:"forward-headers"`        // ForwardHeaders selects the request headers forwarded to the Url, all of them by default.
+	ResponseHeaders   []HeaderRule   `json:"responseHeaders" yaml:"response-headers"`      // ResponseHeaders rewrites the headers of the upstream responses, applied in order.
+	Timeout           int            `json:"timeout" yaml:"timeout"`                       // Timeout (in milliseconds) the upstream has to send the response headers, then each part of the body, 30 seconds by default. The default only applies to the headers of the streamed responses, such as server-sent events and chunked bodies. A negative timeout disables it.
+	Retry             *RetryConfig   `json:"retry" yaml:"retry"`                           // Retry sends the request again when the upstream fails, with an exponential backoff.
+	Pool              *PoolConfig    `json:"pool" yaml:"pool"`                             // Pool configures the connections kept open to the upstream.
+	CircuitBreaker    *BreakerConfig `json:"circuitBreaker" yaml:"circuit-breaker"`        // CircuitBreaker fails fast once the upstream keeps failing, instead of waiting for it.
+}
+
+// RetryConfig sends the redirected request again when the upstream cannot be reached, times out, or replies
+// with one of the StatusCodes. Every duration is in milliseconds.
+type RetryConfig struct {
+	Attempts    int   `json:"attempts" yaml:"attempts"`        // Attempts is the number of retries after the first request.
+	Backoff     int   `json:"backoff" yaml:"backoff"`          // Backoff is the delay before the first retry, doubled for each following one, 100 by default.
+	MaxBackoff  int   `json:"maxBackoff" yaml:"max-backoff"`   // MaxBackoff caps the delay between the retries, unlimited when zero.
+	StatusCodes []int `json:"statusCodes" yaml:"status-codes"` // StatusCodes of the upstream responses that are retried, 502, 503 and 504 by default.
+}
+
+// PoolConfig configures the connections kept open to the upstream. Each setting keeps the default of the Go
+// http.Transport when zero.
+type PoolConfig struct {
+	MaxIdleConns        int `json:"maxIdleConns" yaml:"max-idle-conns"`                 // MaxIdleConns is the number of idle connections kept across every upstream.
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost" yaml:"max-idle-conns-per-host"` // MaxIdleConnsPerHost is the number of idle connections kept per upstream.
+	MaxConnsPerHost     int `json:"maxConnsPerHost" yaml:"max-conns-per-host"`          // MaxConnsPerHost limits the connections open per upstream, the requests over it wait for one to be available.
+	IdleConnTimeout     int `json:"idleConnTimeout" yaml:"idle-conn-timeout"`           // IdleConnTimeout (in milliseconds) an idle connection is kept open.
+}
+
+// BreakerConfig opens the circuit after Threshold consecutive failures of the upstream, which are the requests
+// that cannot be completed and the 5xx responses. While open, the requests are answered with the StatusCode,
+// Body, ContentType and Headers without reaching the upstream. Once OpenDuration has elapsed, the requests reach
+// the upstream again, and the circuit opens again on the next failure, until a request succeeds.
+type BreakerConfig struct {
+	Threshold    int            `json:"threshold" yaml:"threshold"`        // Threshold of consecutive failures opening the circuit, 5 by default.
+	OpenDuration int            `json:"openDuration" yaml:"open-duration"` // OpenDuration (in milliseconds) the circuit stays open, 30 seconds by default.
+	StatusCode   int            `json:"statusCode" yaml:"status-code"`     // StatusCode of the response while the circuit is open, 503 by default.
+	Body         *interface{}   `json:"body" yaml:"body"`                  // Body of the response while the circuit is open, an error message by default.
+	ContentType  string         `json:"contentType" yaml:"content-type"`   // ContentType of the Body, application/json by default.
+	Headers      map[string]any `json:"headers" yaml:"headers"`            // Headers of the response while the circuit is open.
+}
+
+// HeaderRule rewrites a header of the upstream responses, by removing it, replacing its values, or replacing
+// a string in its values, such as the upstream URL of a Location header.
+type HeaderRule struct {
+	Name   string `json:"name" yaml:"name"`     // Name of the header, case-insensitive.
+	Remove bool   `json:"remove" yaml:"remove"` // Remove drops the header from the response.
+	Value  string `json:"value" yaml:"value"`   // Value replaces the values of the header, adding it when missing.
+	Old    string `json:"old" yaml:"old"`       // Old specifies the string replaced by New in the values of the header.
+	New    string `json:"new" yaml:"new"`       // New specifies the replacement of Old.
+}
+
+const (
+	ForwardModeAll  = "all"  // ForwardModeAll forwards every request header, except the hop-by-hop ones.
+	ForwardModeNone = "none" // ForwardModeNone forwards none of the request headers, only the RedirectConfig.Headers.
+)
+
+// ForwardConfig selects the headers of the client request that are forwarded to the redirect URL.
+// The hop-by-hop headers, such as Connection, are never forwarded.
+type ForwardConfig struct {
+	Mode       string   `json:"mode" yaml:"mode"`              // Mode is either ForwardModeAll (default) or ForwardModeNone, before applying the Allow and Deny lists.
+	Allow      []string `json:"allow" yaml:"allow"`            // Allow lists the only headers forwarded when provided, regardless of the Mode.
+	Deny       []string `json:"deny" yaml:"deny"`              // Deny lists the headers never forwarded, such as Cookie.
+	XForwarded bool     `json:"xForwarded" yaml:"x-forwarded"` // XForwarded adds the X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto headers describing the client request.
+}
+
+// Formats of the mock files written by the RecordConfig.
+const (
+	RecordFormatYaml = "yaml"
+	RecordFormatJson = "json"
+)
+
+// RecordConfig writes each redirected request and its response as a mock file, merging the recordings
+// of the same route into one file with a body per request. The recorded files are loaded as any other mock file.
+type RecordConfig struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`              // Enabled turns the recording on.
+	Dir           string `json:"dir" yaml:"dir"`                      // Dir is the directory the mock files are written to, the mock directory by default.
+	Format        string `json:"format" yaml:"format"`                // Format of the mock files, RecordFormatYaml by default or RecordFormatJson.
+	SkipUnmatched bool   `json:"skipUnmatched" yaml:"skip-unmatched"` // SkipUnmatched answers the requests of the recorded routes none of their bodies matches with the OnNoMatch policy of the mock, instead of redirecting and recording them.
 }
 
 type RequestConfig struct {
@@ -26,32 +106,135 @@ type RequestConfig struct {
 	ContentType string `json:"contentType" yaml:"content-type" yaml:"contentType"` // ContentType specifies the media type of the request payload as defined in the RequestConfig struct.
 }
 
+const (
+	MatchingModeStrict = "strict" // MatchingModeStrict requires the request to have exactly the expected values.
+	MatchingModeSubset = "subset" // MatchingModeSubset allows the request to have values other than the expected ones.
+)
+
 type Matching struct {
-	Queries map[string]any `json:"queries" yaml:"queries"` // Queries is a map of key-value pairs used for defining matching query parameters in requests.
-	Paths   map[string]any `json:"paths" yaml:"paths"`     // Paths is a map of key-value pairs used for defining matching path parameters in requests.
-	Headers map[string]any `json:"headers" yaml:"headers"` // Headers is a map of key-value pairs used for defining matching header parameters in requests.
+	Queries       map[string]any `json:"queries" yaml:"queries"`              // Queries is a map of key-value pairs used for defining matching query parameters in requests.
+	Paths         map[string]any `json:"paths" yaml:"paths"`                  // Paths is a map of key-value pairs used for defining matching path parameters in requests.
+	Headers       map[string]any `json:"headers" yaml:"headers"`              // Headers is a map of key-value pairs used for defining matching header parameters in requests.
+	Body          *BodyMatching  `json:"body" yaml:"body"`                    // Body defines the criteria the request payload must satisfy.
+	QueryMode     string         `json:"queryMode" yaml:"query-mode"`         // QueryMode is either MatchingModeStrict (default) or MatchingModeSubset for the query parameters.
+	IgnoreQueries []string       `json:"ignoreQueries" yaml:"ignore-queries"` // IgnoreQueries lists the query parameters that are not taken into account, such as cache busters.
+	PathMode      string         `json:"pathMode" yaml:"path-mode"`           // PathMode is either MatchingModeStrict (default) or MatchingModeSubset for the path values.
+	IgnorePaths   []string       `json:"ignorePaths" yaml:"ignore-paths"`     // IgnorePaths lists the path values that are not taken into account.
 }
+
+type BodyMatching struct {
+	EqualTo  any            `json:"equalTo" yaml:"equal-to"`   // EqualTo requires the JSON payload to be exactly equal to the given document.
+	Partial  any            `json:"partial" yaml:"partial"`    // Partial requires the JSON payload to contain at least the fields of the given document.
+	JsonPath map[string]any `json:"jsonPath" yaml:"json-path"` // JsonPath maps JSONPath expressions to the matching value that any of the found values must satisfy.
+	Form     map[string]any `json:"form" yaml:"form"`          // Form maps application/x-www-form-urlencoded fields to their matching value.
+	Regex    string         `json:"regex" yaml:"regex"`        // Regex requires the raw payload to match the given regular expression.
+}
+
+// ScenarioStateStarted is the initial state of every scenario.
+const ScenarioStateStarted = "Started"
+
+// EncodingBase64 is the ResponseBody.Encoding used to provide binary content as a base64 string.
+const EncodingBase64 = "base64"
+
 type ResponseBody struct {
-	Body     *interface{}    `json:"body" yaml:"body"`         // Body represents the dynamic content of the response, serialized based on the provided JSON or YAML format.
-	Matching *Matching       `json:"matching" yaml:"matching"` // Matching handles product retrieval. Filters the body with matching queries, headers, and path parameters if provided.
-	Headers  *map[string]any `json:"headers" yaml:"headers"`   // Headers in case that need to add headers to the response
+	Body          *interface{}    `json:"body" yaml:"body"`                    // Body represents the dynamic content of the response, serialized based on the provided JSON or YAML format.
+	Matching      *Matching       `json:"matching" yaml:"matching"`            // Matching handles product retrieval. Filters the body with matching queries, headers, and path parameters if provided.
+	Headers       *map[string]any `json:"headers" yaml:"headers"`              // Headers in case that need to add headers to the response
+	Priority      int             `json:"priority" yaml:"priority"`            // Priority takes precedence over the matching score when several bodies match the request, the higher the preferred.
+	StatusCode    int             `json:"statusCode" yaml:"status-code"`       // StatusCode overrides the ResponseConfig.StatusCode for this body.
+	Delay         *int            `json:"delay" yaml:"delay"`                  // Delay overrides the ResponseConfig.Delay (in milliseconds) for this body, a delay of 0 sending it right away.
+	ContentType   string          `json:"contentType" yaml:"content-type"`     // ContentType overrides the ResponseConfig.ContentType for this body.
+	Encoding      string          `json:"encoding" yaml:"encoding"`            // Encoding of the string body, EncodingBase64 for binary content. The string is written as it is when empty.
+	BodyFile      string          `json:"bodyFile" yaml:"body-file"`           // BodyFile is the path of a file, relative to the mock file, streamed as the response body instead of Body.
+	Scenario      string          `json:"scenario" yaml:"scenario"`            // Scenario is the name of the scenario whose state this body depends on or changes.
+	RequiredState string          `json:"requiredState" yaml:"required-state"` // RequiredState is the state the Scenario must be in for this body to match.
+	NewState      string          `json:"newState" yaml:"new-state"`           // NewState is the state the Scenario moves to once this body is returned.
+	Weight        int             `json:"weight" yaml:"weight"`                // Weight of the body when using the StrategyWeightedRandom, 1 by default.
+	Fault         string          `json:"fault" yaml:"fault"`                  // Fault overrides the ResponseConfig.Fault for this body.
+	Latency       *LatencyConfig  `json:"latency" yaml:"latency"`              // Latency overrides the ResponseConfig.Latency for this body.
+}
+
+// Faults simulated by ResponseConfig.Fault and ResponseBody.Fault instead of writing a regular response.
+const (
+	FaultEmptyResponse    = "empty-response"     // FaultEmptyResponse closes the connection without any reply.
+	FaultConnectionReset  = "connection-reset"   // FaultConnectionReset resets the connection without any reply.
+	FaultHangAfterHeaders = "hang-after-headers" // FaultHangAfterHeaders sends the headers, then hangs until the client gives up.
+	FaultTruncatedBody    = "truncated-body"     // FaultTruncatedBody sends half of the body with a larger Content-Length, then closes the connection.
+	FaultMalformedJson    = "malformed-json"     // FaultMalformedJson sends a corrupted version of the body.
+)
+
+// Strategies used by ResponseConfig.Strategy to select the body to return among the matching ones.
+const (
+	StrategyBestMatch      = "best-match"      // StrategyBestMatch returns the body with the highest priority, then the most specific matching.
+	StrategySequence       = "sequence"        // StrategySequence returns each body in order, then sticks to the last one unless ResponseConfig.Loop is enabled.
+	StrategyRoundRobin     = "round-robin"     // StrategyRoundRobin returns each body in order, starting over after the last one.
+	StrategyWeightedRandom = "weighted-random" // StrategyWeightedRandom returns a random body, proportionally to its ResponseBody.Weight.
+)
+
+// Distributions used by LatencyConfig.Distribution to sample the delay before the response is sent.
+const (
+	DistributionUniform     = "uniform"     // DistributionUniform samples a delay evenly between LatencyConfig.Min and LatencyConfig.Max.
+	DistributionNormal      = "normal"      // DistributionNormal samples a delay around LatencyConfig.Mean, with the LatencyConfig.StdDev.
+	DistributionLogNormal   = "lognormal"   // DistributionLogNormal samples a long-tailed delay with the LatencyConfig.Mean and LatencyConfig.StdDev.
+	DistributionPercentiles = "percentiles" // DistributionPercentiles samples a delay matching the LatencyConfig.Percentiles, such as {p50: 20, p99: 500}.
+)
+
+// LatencyConfig simulates a realistic network, with random delays and a limited bandwidth. Every duration is in milliseconds.
+type LatencyConfig struct {
+	Distribution   string         `json:"distribution" yaml:"distribution"`       // Distribution of the delay, such as DistributionUniform. No delay is added when empty.
+	Min            int            `json:"min" yaml:"min"`                         // Min is the lowest delay, the sampled delays are clamped to it.
+	Max            int            `json:"max" yaml:"max"`                         // Max is the highest delay, the sampled delays are clamped to it when provided.
+	Mean           float64        `json:"mean" yaml:"mean"`                       // Mean of the DistributionNormal and DistributionLogNormal delays.
+	StdDev         float64        `json:"stdDev" yaml:"std-dev"`                  // StdDev is the standard deviation of the DistributionNormal and DistributionLogNormal delays.
+	Percentiles    map[string]int `json:"percentiles" yaml:"percentiles"`         // Percentiles of the DistributionPercentiles delays, keyed by percentile such as p50, p95 or p99.
+	BytesPerSecond int            `json:"bytesPerSecond" yaml:"bytes-per-second"` // BytesPerSecond throttles the response body to this bandwidth, unlimited when zero.
 }
 
 type ResponseConfig struct {
-	ContentType string         `json:"contentType" yaml:"content-type" yaml:"contentType"`
-	StatusCode  int            `json:"statusCode" yaml:"status-code" yaml:"statusCode"` // StatusCode represents the HTTP status code to return in the response.
-	Delay       int            `json:"delay" yaml:"delay"`                              // Delay specifies the time delay (in milliseconds) before the response is sent.
-	Bodies      []ResponseBody `json:"bodies" yaml:"bodies"`                            // Bodies contains multiple response bodies to choose from. If no matching filter is set for the body, the first body will be returned.
+	ContentType string         `json:"contentType" yaml:"content-type" yaml:"contentType"` // ContentType is the Content-Type header of the response, application/json by default.
+	StatusCode  int            `json:"statusCode" yaml:"status-code" yaml:"statusCode"`    // StatusCode represents the HTTP status code to return in the response.
+	Delay       int            `json:"delay" yaml:"delay"`                                 // Delay specifies the time delay (in milliseconds) before the response is sent.
+	Bodies      []ResponseBody `json:"bodies" yaml:"bodies"`                               // Bodies contains multiple response bodies to choose from. The body with the highest priority, then the most specific matching, is returned.
+	Template    bool           `json:"template" yaml:"template"`                           // Template enables rendering the bodies and headers as Go templates with access to the request data.
+	Strategy    string         `json:"strategy" yaml:"strategy"`                           // Strategy selects the body to return among the matching ones, StrategyBestMatch by default.
+	Loop        bool           `json:"loop" yaml:"loop"`                                   // Loop makes the StrategySequence start over after the last body instead of sticking to it.
+	Fault       string         `json:"fault" yaml:"fault"`                                 // Fault simulates a network failure instead of writing the response, such as FaultConnectionReset.
+	Latency     *LatencyConfig `json:"latency" yaml:"latency"`                             // Latency simulates a random delay and a limited bandwidth, used when no fixed Delay is provided.
+	OnNoMatch   string         `json:"onNoMatch" yaml:"on-no-match"`                       // OnNoMatch is the policy applied when none of the Bodies matches the request, OnNoMatchNotFound by default.
+	DefaultBody *ResponseBody  `json:"defaultBody" yaml:"default-body"`                    // DefaultBody is returned by the OnNoMatchDefaultBody policy.
 }
 
+// Policies used by ResponseConfig.OnNoMatch when none of the bodies of a mock matches the request.
+const (
+	OnNoMatchNotFound       = "not-found"       // OnNoMatchNotFound answers with a 404 describing why each body does not match.
+	OnNoMatchDefaultBody    = "default-body"    // OnNoMatchDefaultBody returns the ResponseConfig.DefaultBody.
+	OnNoMatchRedirect       = "redirect"        // OnNoMatchRedirect forwards the request to the redirect of the mock, which is not used otherwise.
+	OnNoMatchGlobalRedirect = "global-redirect" // OnNoMatchGlobalRedirect forwards the request to the redirect of the configuration file.
+)
+
 type MockServerConfig struct {
-	RedirectConfig *RedirectConfig `yaml:"redirect"`     // RedirectConfig contains settings for handling HTTP redirections.
-	Port           string          `yaml:"port"`         // Port specifies the port on which the mock server will run.
-	MockPath       string          `yaml:"mock"`         // MockPath defines the path to the mock configuration files.
-	ContextPath    string          `yaml:"context-path"` // ContextPath sets the base path or prefix for all routes handled by the mock server.
+	RedirectConfig  *RedirectConfig `yaml:"redirect"`          // RedirectConfig contains settings for handling HTTP redirections.
+	Port            string          `yaml:"port"`              // Port specifies the port on which the mock server will run.
+	MockPath        string          `yaml:"mock"`              // MockPath defines the path to the mock configuration files.
+	ContextPath     string          `yaml:"context-path"`      // ContextPath sets the base path or prefix for all routes handled by the mock server.
+	Latency         *LatencyConfig  `yaml:"latency"`           // Latency is the default latency of every mock response that does not provide its own delay or latency.
+	Chaos           *ChaosConfig    `yaml:"chaos"`             // Chaos randomly injects errors, latency and connection drops on every route.
+	JournalSize     int             `yaml:"journal-size"`      // JournalSize is the number of requests kept by the request journal, 1000 by default. A negative size disables it.
+	JournalBodySize int             `yaml:"journal-body-size"` // JournalBodySize is the number of bytes of each request body kept by the request journal, 64 KiB by default. A negative size keeps none.
+	Debug           bool            `yaml:"debug"`             // Debug answers the unmatched requests with the mocks closest to them and why they do not match.
+	Seed            int64           `yaml:"seed"`              // Seed makes the weighted-random selection of the bodies reproducible across runs, a random one is used when zero.
 }
 
-var (
-	MockConfigResponses []MockConfigResponse
-	Config              *MockServerConfig
-)
+// ChaosConfig randomly injects errors, latency and connection drops on every route but the admin ones. Each rate is a
+// probability between 0 and 1, rolled for every request.
+type ChaosConfig struct {
+	Enabled          bool           `json:"enabled" yaml:"enabled"`                     // Enabled turns the chaos on. It can be toggled at runtime through the admin endpoints.
+	Seed             int64          `json:"seed" yaml:"seed"`                           // Seed makes the chaos reproducible across runs, a random one is used when zero.
+	ErrorRate        float64        `json:"errorRate" yaml:"error-rate"`                // ErrorRate is the probability of replying with one of the ErrorStatusCodes.
+	ErrorStatusCodes []int          `json:"errorStatusCodes" yaml:"error-status-codes"` // ErrorStatusCodes to pick from randomly, 500, 502 and 503 by default.
+	LatencyRate      float64        `json:"latencyRate" yaml:"latency-rate"`            // LatencyRate is the probability of adding the Latency before the response.
+	Latency          *LatencyConfig `json:"latency" yaml:"latency"`                     // Latency added to the request, a uniform delay between 100 and 1000 milliseconds by default.
+	DropRate         float64        `json:"dropRate" yaml:"drop-rate"`                  // DropRate is the probability of resetting the connection without any reply.
+	Paths            []string       `json:"paths" yaml:"paths"`                         // Paths scopes the chaos to the request paths matching these globs, such as /api/orders/*. Every path when empty.
+	Methods          []string       `json:"methods" yaml:"methods"`                     // Methods scopes the chaos to these request methods. Every method when empty.
+}